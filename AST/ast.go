@@ -3,12 +3,14 @@ package ast
 import (
     "A-Plus-Plus/token"
     "bytes"
+    "math/big"
     "strings"
 )
 
 type Node interface {
     TokenLiteral() string
     String() string
+    Pos() token.Position // where the node starts in the source
 }
 
 type Statement interface {
@@ -33,6 +35,13 @@ func (p *Program) TokenLiteral() string {
     }
 }
 
+func (p *Program) Pos() token.Position {
+    if len(p.Statements) > 0 {
+        return p.Statements[0].Pos()
+    }
+    return token.Position{}
+}
+
 func (p *Program) String() string {
     var out bytes.Buffer
 
@@ -51,9 +60,11 @@ type Identifier struct {
 
 func (i *Identifier) expressionNode() {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
+func (i *Identifier) Pos() token.Position { return i.Token.Pos }
 func (i *Identifier) String() string { return i.Value }
 
 
+// Also used for const, which only differs in Token
 type LetStatement struct {
     Token token.Token // token.LET token
     Name *Identifier
@@ -62,6 +73,7 @@ type LetStatement struct {
 
 func (ls *LetStatement) statementNode() {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Pos() token.Position { return ls.Token.Pos }
 func (ls *LetStatement) String() string {
     var out bytes.Buffer
 
@@ -84,6 +96,7 @@ type ReturnStatement struct {
 }
 func (rs *ReturnStatement) statementNode() {}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReturnStatement) Pos() token.Position { return rs.Token.Pos }
 func (rs *ReturnStatement) String() string {
     var out bytes.Buffer
     out.WriteString(rs.TokenLiteral() + " ")
@@ -96,6 +109,86 @@ func (rs *ReturnStatement) String() string {
 }
 
 
+type ThrowStatement struct {
+    Token token.Token // token.THROW
+    Value Expression
+}
+func (ts *ThrowStatement) statementNode() {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) Pos() token.Position { return ts.Token.Pos }
+func (ts *ThrowStatement) String() string {
+    var out bytes.Buffer
+    out.WriteString(ts.TokenLiteral() + " ")
+
+    if ts.Value != nil {
+        out.WriteString(ts.Value.String())
+    }
+    out.WriteString(";")
+    return out.String()
+}
+
+
+type WhileStatement struct {
+    Token     token.Token // the while token
+    Condition Expression
+    Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode() {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) Pos() token.Position { return ws.Token.Pos }
+func (ws *WhileStatement) String() string {
+    var out bytes.Buffer
+
+    out.WriteString("while")
+    out.WriteString(ws.Condition.String())
+    out.WriteString(" ")
+    out.WriteString(ws.Body.String())
+
+    return out.String()
+}
+
+
+// for (x in iterable) { } or for (k, v in iterable) { }
+type ForStatement struct {
+    Token    token.Token // the for token
+    Key      *Identifier // the only variable in the one variable form
+    Value    *Identifier // nil in the one variable form
+    Iterable Expression
+    Body     *BlockStatement
+}
+
+func (fs *ForStatement) statementNode() {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) Pos() token.Position { return fs.Token.Pos }
+func (fs *ForStatement) String() string {
+    var out bytes.Buffer
+
+    out.WriteString("for(")
+    out.WriteString(fs.Key.String())
+    if fs.Value != nil {
+        out.WriteString(", " + fs.Value.String())
+    }
+    out.WriteString(" in ")
+    out.WriteString(fs.Iterable.String())
+    out.WriteString(") ")
+    out.WriteString(fs.Body.String())
+
+    return out.String()
+}
+
+
+// break or continue
+type BranchStatement struct {
+    Token token.Token // token.BREAK or token.CONTINUE
+}
+
+func (bs *BranchStatement) statementNode() {}
+func (bs *BranchStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BranchStatement) Pos() token.Position { return bs.Token.Pos }
+func (bs *BranchStatement) String() string { return bs.Token.Literal + ";" }
+
+
 type ExpressionStatement struct {
     Token token.Token
     Expression Expression
@@ -103,6 +196,7 @@ type ExpressionStatement struct {
 
 func (es *ExpressionStatement) statementNode() {}
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) Pos() token.Position { return es.Token.Pos }
 func (es *ExpressionStatement) String() string {
     if es.Expression != nil {
         return es.Expression.String()
@@ -115,14 +209,28 @@ func (es *ExpressionStatement) String() string {
 type IntegerLiteral struct {
     Token token.Token
     Value int64
+    Big *big.Int // set instead of Value when the literal does not fit in an int64
 }
 
 func (il *IntegerLiteral) expressionNode() {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() token.Position { return il.Token.Pos }
 func (il *IntegerLiteral) String() string { return il.Token.Literal }
 
 
 
+type FloatLiteral struct {
+    Token token.Token
+    Value float64
+}
+
+func (fl *FloatLiteral) expressionNode() {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() token.Position { return fl.Token.Pos }
+func (fl *FloatLiteral) String() string { return fl.Token.Literal }
+
+
+
 type PrefixExpression struct {
     Token token.Token // the prefix token (! or -)
     Operator string
@@ -131,6 +239,7 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode() { }
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() token.Position { return pe.Token.Pos }
 func (pe *PrefixExpression) String() string {
     var out bytes.Buffer
 
@@ -154,6 +263,12 @@ type InfixExpression struct {
 
 func (ie *InfixExpression) expressionNode() {}
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) Pos() token.Position {
+    if ie.Left != nil {
+        return ie.Left.Pos()
+    }
+    return ie.Token.Pos
+}
 func (ie *InfixExpression) String() string {
     var out bytes.Buffer
 
@@ -175,6 +290,7 @@ type Boolean struct {
 
 func (b *Boolean) expressionNode() {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
+func (b *Boolean) Pos() token.Position { return b.Token.Pos }
 func (b *Boolean) String() string { return b.Token.Literal }
 
 
@@ -189,6 +305,7 @@ type IfExpression struct {
 
 func (ie *IfExpression) expressionNode() {}
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() token.Position { return ie.Token.Pos }
 func (ie *IfExpression) String() string {
     var out bytes.Buffer
 
@@ -206,6 +323,41 @@ func (ie *IfExpression) String() string {
 }
 
 
+type TryExpression struct {
+    Token       token.Token // the try token
+    Block       *BlockStatement
+    Param       *Identifier // the error in catch (e), nil when the catch has no parameter
+    Catch       *BlockStatement // nil when there is only a finally
+    Finally     *BlockStatement // nil when there is only a catch
+}
+
+func (te *TryExpression) expressionNode() {}
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TryExpression) Pos() token.Position { return te.Token.Pos }
+func (te *TryExpression) String() string {
+    var out bytes.Buffer
+
+    out.WriteString("try ")
+    out.WriteString(te.Block.String())
+
+    if te.Catch != nil {
+        out.WriteString(" catch")
+        if te.Param != nil {
+            out.WriteString("(" + te.Param.String() + ")")
+        }
+        out.WriteString(" ")
+        out.WriteString(te.Catch.String())
+    }
+
+    if te.Finally != nil {
+        out.WriteString(" finally ")
+        out.WriteString(te.Finally.String())
+    }
+
+    return out.String()
+}
+
+
 type BlockStatement struct {
     Token       token.Token   // the { token
     Statements  []Statement
@@ -213,6 +365,7 @@ type BlockStatement struct {
 
 func (bs *BlockStatement) expressionNode() {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() token.Position { return bs.Token.Pos }
 func (bs *BlockStatement) String() string {
     var out bytes.Buffer
 
@@ -227,12 +380,14 @@ func (bs *BlockStatement) String() string {
 
 type FunctionLiteral struct {
     Token       token.Token // the 'fn' token
+    Name        string // the let binding it is assigned to, if any. Used in stack traces
     Parameters  []*Identifier
     Body        *BlockStatement
 }
 
 func (fl *FunctionLiteral) expressionNode() { }
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() token.Position { return fl.Token.Pos }
 func (fl *FunctionLiteral) String() string {
     var out bytes.Buffer
 
@@ -260,6 +415,12 @@ type CallExpression struct {
 
 func (ce *CallExpression) expressionNode() {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() token.Position {
+    if ce.Function != nil {
+        return ce.Function.Pos()
+    }
+    return ce.Token.Pos
+}
 func (ce *CallExpression) String() string {
     var out bytes.Buffer
 
@@ -285,10 +446,33 @@ type StringLiteral struct {
 
 func (sl *StringLiteral) expressionNode() {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() token.Position { return sl.Token.Pos }
 func (sl *StringLiteral) String() string { return sl.Token.Literal }
 
 
 
+// "a ${x} b". Parts are the expressions in order, with the text between them as StringLiterals
+type InterpolatedString struct {
+    Token token.Token // the INTERP_START token
+    Parts []Expression
+}
+
+func (is *InterpolatedString) expressionNode() {}
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+func (is *InterpolatedString) Pos() token.Position { return is.Token.Pos }
+func (is *InterpolatedString) String() string {
+    var out bytes.Buffer
+    for _, part := range is.Parts {
+        if text, ok := part.(*StringLiteral); ok {
+            out.WriteString(text.Value)
+        } else {
+            out.WriteString("${" + part.String() + "}")
+        }
+    }
+    return out.String()
+}
+
+
 type ArrayLiteral struct {
     Token token.Token   // the '[' token
     Elements []Expression
@@ -296,6 +480,7 @@ type ArrayLiteral struct {
 
 func (al *ArrayLiteral) expressionNode() {}
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() token.Position { return al.Token.Pos }
 func (al *ArrayLiteral) String() string {
     var out bytes.Buffer
     elements := []string {}
@@ -310,6 +495,31 @@ func (al *ArrayLiteral) String() string {
 
 
 
+// x = value, or a compound form like x += value. Target is an Identifier or IndexExpression
+type AssignExpression struct {
+    Token    token.Token // the assignment operator
+    Target   Expression
+    Operator string
+    Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode() {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) Pos() token.Position {
+    if ae.Target != nil {
+        return ae.Target.Pos()
+    }
+    return ae.Token.Pos
+}
+func (ae *AssignExpression) String() string {
+    var out bytes.Buffer
+    out.WriteString(ae.Target.String())
+    out.WriteString(" " + ae.Operator + " ")
+    out.WriteString(ae.Value.String())
+    return out.String()
+}
+
+
 type IndexExpression struct {
     Token token.Token  // the '[' token
     Left Expression
@@ -318,6 +528,12 @@ type IndexExpression struct {
 
 func (ie *IndexExpression) expressionNode() {}
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() token.Position {
+    if ie.Left != nil {
+        return ie.Left.Pos()
+    }
+    return ie.Token.Pos
+}
 func (ie *IndexExpression) String() string {
     var out bytes.Buffer
     out.WriteString("(")
@@ -328,20 +544,59 @@ func (ie *IndexExpression) String() string {
     return out.String()
 }
 
+// left[start:end] or left[start:end:step]. Any of the three can be left out, and is nil then
+type SliceExpression struct {
+    Token token.Token  // the '[' token
+    Left Expression
+    Start Expression
+    End Expression
+    Step Expression
+}
+
+func (se *SliceExpression) expressionNode() {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) Pos() token.Position {
+    if se.Left != nil {
+        return se.Left.Pos()
+    }
+    return se.Token.Pos
+}
+func (se *SliceExpression) String() string {
+    var out bytes.Buffer
+    out.WriteString("(")
+    out.WriteString(se.Left.String())
+    out.WriteString("[")
+    if se.Start != nil {
+        out.WriteString(se.Start.String())
+    }
+    out.WriteString(":")
+    if se.End != nil {
+        out.WriteString(se.End.String())
+    }
+    if se.Step != nil {
+        out.WriteString(":")
+        out.WriteString(se.Step.String())
+    }
+    out.WriteString("])")
+    return out.String()
+}
+
 
 
 type HashLiteral struct {
     Token token.Token   // the '{' token
     Pairs map[Expression]Expression
+    Keys []Expression // the keys of Pairs in the order they were written
 }
 
 func (hl *HashLiteral) expressionNode() {}
 func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Pos() token.Position { return hl.Token.Pos }
 func (hl *HashLiteral) String() string {
     var out bytes.Buffer
     pairs := []string{}
-    for key, value := range hl.Pairs {
-        pairs = append(pairs, key.String()+":"+value.String())
+    for _, key := range hl.Keys {
+        pairs = append(pairs, key.String()+":"+hl.Pairs[key].String())
     }
     out.WriteString("{")
     out.WriteString(strings.Join(pairs, ", "))
@@ -3,6 +3,7 @@ package evaluator
 import (
 	"A-Plus-Plus/object"
 	"fmt"
+	"unicode/utf8"
 )
 
 var builtins = map[string]*object.Builtin {
@@ -13,7 +14,8 @@ var builtins = map[string]*object.Builtin {
             }
             switch arg := args[0].(type) {
             case *object.String:
-                return &object.Integer{Value: int64(len(arg.Value))}
+                // characters, not bytes, so "héllo" has 5
+                return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
             case *object.Array:
                 return &object.Integer{Value: int64(len(arg.Elements))}
             default:
@@ -21,6 +23,36 @@ var builtins = map[string]*object.Builtin {
             }
         },
     },
+    // The size of a string in UTF-8 bytes
+    "bytelen": {
+        Fn: func(args ...object.Object) object.Object {
+            if len(args) != 1 {
+                return newError("wrong number of arguments. got=%d, want=1", len(args))
+            }
+            str, ok := args[0].(*object.String)
+            if !ok {
+                return newError("argument to `bytelen` must be STRING, got=%s", args[0].Type())
+            }
+            return &object.Integer{Value: int64(len(str.Value))}
+        },
+    },
+    // The UTF-8 bytes of a string, as an array of integers from 0 to 255
+    "bytes": {
+        Fn: func(args ...object.Object) object.Object {
+            if len(args) != 1 {
+                return newError("wrong number of arguments. got=%d, want=1", len(args))
+            }
+            str, ok := args[0].(*object.String)
+            if !ok {
+                return newError("argument to `bytes` must be STRING, got=%s", args[0].Type())
+            }
+            elements := make([]object.Object, len(str.Value))
+            for i := 0; i < len(str.Value); i++ {
+                elements[i] = &object.Integer{Value: int64(str.Value[i])}
+            }
+            return &object.Array{Elements: elements}
+        },
+    },
     "first": {
         Fn: func(args ...object.Object) object.Object {
             if len(args) != 1 {
@@ -88,6 +120,56 @@ var builtins = map[string]*object.Builtin {
             return &object.Array{Elements: newElements}
         },
     },
+    "error": {
+        Fn: func(args ...object.Object) object.Object {
+            if len(args) != 1 {
+                return newError("wrong number of arguments. got=%d, want=1", len(args))
+            }
+            return errorFromValue(args[0])
+        },
+    },
+    // Makes a hash read-only, so it can be used as a key in other hashes
+    "freeze": {
+        Fn: func(args ...object.Object) object.Object {
+            if len(args) != 1 {
+                return newError("wrong number of arguments. got=%d, want=1", len(args))
+            }
+            hash, ok := args[0].(*object.Hash)
+            if !ok {
+                return newError("argument to `freeze` must be HASH, got=%s", args[0].Type())
+            }
+            hash.Frozen = true
+            return hash
+        },
+    },
+    // range(end), range(start, end) or range(start, end, step)
+    "range": {
+        Fn: func(args ...object.Object) object.Object {
+            if len(args) < 1 || len(args) > 3 {
+                return newError("wrong number of arguments. got=%d, want=1 to 3", len(args))
+            }
+            bounds := []int64{}
+            for _, arg := range args {
+                integer, ok := arg.(*object.Integer)
+                if !ok || integer.Big != nil {
+                    return newError("arguments to `range` must be 64-bit INTEGER, got=%s", arg.Type())
+                }
+                bounds = append(bounds, integer.Value)
+            }
+
+            r := &object.Range{End: bounds[0], Step: 1}
+            if len(bounds) > 1 {
+                r.Start, r.End = bounds[0], bounds[1]
+            }
+            if len(bounds) > 2 {
+                r.Step = bounds[2]
+            }
+            if r.Step == 0 {
+                return newError("range step must not be zero")
+            }
+            return r
+        },
+    },
     "print": &object.Builtin{
         Fn: func(args ...object.Object) object.Object {
             for _, arg := range args {
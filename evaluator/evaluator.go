@@ -3,16 +3,56 @@ package evaluator
 import (
     "A-Plus-Plus/ast"
     "A-Plus-Plus/object"
+    "A-Plus-Plus/token"
+    "bytes"
     "fmt"
+    "math"
+    "math/big"
 )
 
 var (
     NULL = &object.Null{}
     TRUE = &object.Boolean{Value: true}
     FALSE = &object.Boolean{Value: false}
+    BREAK = &object.Break{}
+    CONTINUE = &object.Continue{}
 )
 
+// How deep calls that are not tail calls may nest before we give up with an error, long before Go
+// would run out of stack
+var MaxCallDepth = 10000
+
+// Integer arithmetic that overflows int64 normally carries on with big integers. When this is set,
+// it returns an error instead
+var CheckedArithmetic = false
+
+// How many bits an integer made by ** or << may have. They are worked out in full before anything
+// could stop them, so without a limit one expression could use up all the memory
+var MaxIntegerBits = 1 << 20
+
+// Indexing an array or string past either end normally gives null. When this is set, it returns an
+// error instead. Slices are cut down to fit either way
+var StrictIndexing = false
+
 func Eval(node ast.Node, env *object.Environment) object.Object {
+    return annotateError(eval(node, env), node, env)
+}
+
+// Errors bubble up through every Eval on the way out, so the first one to see it is the
+// innermost node that caused it, and the call stack is still the one it happened in
+func annotateError(result object.Object, node ast.Node, env *object.Environment) object.Object {
+    if err, ok := result.(*object.Error); ok {
+        if !err.Pos.IsValid() {
+            err.Pos = node.Pos()
+        }
+        if err.Trace == nil {
+            err.Trace = env.CallStack().Trace()
+        }
+    }
+    return result
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
     switch node := node.(type) {
     case *ast.Program:
         return evalProgram(node.Statements, env)
@@ -21,8 +61,14 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
         return Eval(node.Expression, env)
 
     case *ast.IntegerLiteral:
+        if node.Big != nil {
+            return object.NewBigInteger(node.Big)
+        }
         return &object.Integer{Value: node.Value}
 
+    case *ast.FloatLiteral:
+        return &object.Float{Value: node.Value}
+
     case *ast.Boolean:
         return nativeBoolToBooleanObject(node.Value)
 
@@ -34,6 +80,12 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
         return evalPrefixExpression(node.Operator, right, env)
 
     case *ast.InfixExpression:
+        if isLogical(node) {
+            if left, done := evalLogicalLeft(node, env); done {
+                return left
+            }
+            return Eval(node.Right, env)
+        }
         left := Eval(node.Left, env)
         if isError(left) {
             return left
@@ -47,8 +99,19 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
     case *ast.IfExpression:
         return evalIfExpression(node, env)
 
+    case *ast.TryExpression:
+        return evalTryExpression(node, env)
+
+    case *ast.ThrowStatement:
+        val := Eval(node.Value, env)
+        if isError(val) {
+            return val
+        }
+        return errorFromValue(val)
+
     case *ast.ReturnStatement:
-        val := Eval(node.ReturnValue, env)
+        // whatever is returned is in tail position, wherever the return is
+        val := evalTailExpression(node.ReturnValue, env)
         if isError(val) {
             return val
         }
@@ -57,12 +120,26 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
     case *ast.BlockStatement:
         return evalBlockStatement(node, env)
 
+    case *ast.WhileStatement:
+        return evalWhileStatement(node, env)
+
+    case *ast.ForStatement:
+        return evalForStatement(node, env)
+
+    case *ast.BranchStatement:
+        if node.Token.Type == token.BREAK {
+            return BREAK
+        }
+        return CONTINUE
+
     case *ast.LetStatement:
         val := Eval(node.Value, env)
         if isError(val) {
             return val
         }
-        env.Set(node.Name.Value, val)
+        if !env.Define(node.Name.Value, val, node.Token.Type == token.CONST) {
+            return newError("cannot redeclare constant %s", node.Name.Value)
+        }
 
     case *ast.Identifier:
         return evalIdentifier(node, env)
@@ -70,7 +147,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
     case *ast.FunctionLiteral:
         params := node.Parameters
         body := node.Body
-        return &object.Function{Parameters: params, Env: env, Body: body}
+        return &object.Function{Name: node.Name, Parameters: params, Env: env, Body: body}
 
     case *ast.CallExpression:
         function := Eval(node.Function, env)
@@ -81,9 +158,11 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
         if len(args) == 1 && isError(args[0]) {
             return args[0]
         }
-        return applyFunction(function, args)
+        return applyFunction(function, args, node.Pos(), env)
     case *ast.StringLiteral:
         return &object.String{Value: node.Value}
+    case *ast.InterpolatedString:
+        return evalInterpolatedString(node, env)
     case *ast.ArrayLiteral:
         elements := evalExpressions(node.Elements, env)
         if len(elements) == 1 && isError(elements[0]) {
@@ -100,22 +179,26 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
             return index
         }
         return evalIndexExpression(left, index)
+    case *ast.SliceExpression:
+        return evalSliceExpression(node, env)
     case *ast.HashLiteral:
         return evalHashLiteral(node, env)
+    case *ast.AssignExpression:
+        return evalAssignExpression(node, env)
     }
     return nil
 }
 
 
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-    pairs := make(map[object.HashKey]object.HashPair)
-    for keyNode, valueNode := range node.Pairs {
+    hash := object.NewHash()
+    for _, keyNode := range node.Keys {
+        valueNode := node.Pairs[keyNode]
         key := Eval(keyNode, env)
         if isError(key) {
             return key
         }
-        hashKey, ok := key.(object.Hashable)
-        if !ok {
+        if !object.IsHashable(key) {
             return newError("unusable as hash key: %s", key.Type())
         }
 
@@ -124,11 +207,136 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
             return value
         }
 
-        hashed := hashKey.HashKey()
-        pairs[hashed] = object.HashPair{Key: key, Value: value}
+        hash.Set(key, value)
     }
 
-    return &object.Hash{Pairs: pairs}
+    return hash
+}
+
+
+// For the compound forms the current value is read before the new one is evaluated
+func evalAssignExpression(ae *ast.AssignExpression, env *object.Environment) object.Object {
+    // += becomes +
+    operator := ae.Operator[:len(ae.Operator)-1]
+
+    switch target := ae.Target.(type) {
+    case *ast.Identifier:
+        var current object.Object
+        if operator != "" {
+            current = evalIdentifier(target, env)
+            if isError(current) {
+                return current
+            }
+        }
+        if env.IsConst(target.Value) {
+            return newError("cannot assign to constant %s", target.Value)
+        }
+        val := evalAssignedValue(operator, current, ae.Value, env)
+        if isError(val) {
+            return val
+        }
+        if !env.Assign(target.Value, val) {
+            return newError("cannot assign to undeclared identifier: %s", target.Value)
+        }
+        return val
+
+    case *ast.IndexExpression:
+        left := Eval(target.Left, env)
+        if isError(left) {
+            return left
+        }
+        index := Eval(target.Index, env)
+        if isError(index) {
+            return index
+        }
+        var current object.Object
+        if operator != "" {
+            current = evalIndexExpression(left, index)
+            if isError(current) {
+                return current
+            }
+        }
+        val := evalAssignedValue(operator, current, ae.Value, env)
+        if isError(val) {
+            return val
+        }
+        return evalIndexAssignment(left, index, val)
+
+    default:
+        return newError("cannot assign to %s", ae.Target.String())
+    }
+}
+
+
+// The value to store: value itself for =, or current combined with it for the compound forms
+func evalAssignedValue(operator string, current object.Object, value ast.Expression, env *object.Environment) object.Object {
+    val := Eval(value, env)
+    if isError(val) || operator == "" {
+        return val
+    }
+    return evalInfixExpression(operator, current, val, env)
+}
+
+
+func evalIndexAssignment(left, index, val object.Object) object.Object {
+    switch left := left.(type) {
+    case *object.Array:
+        idx, ok := index.(*object.Integer)
+        if !ok {
+            return newError("array index must be INTEGER, got=%s", index.Type())
+        }
+        i, ok := sequenceIndex(idx, len(left.Elements))
+        if !ok {
+            return newError("index out of range: %s", idx.Inspect())
+        }
+        left.Elements[i] = val
+        return val
+
+    case *object.Hash:
+        if left.Frozen {
+            return newError("cannot assign to a frozen hash")
+        }
+        if !object.IsHashable(index) {
+            return newError("unusable as hash key: %s", index.Type())
+        }
+        left.Set(index, val)
+        return val
+
+    default:
+        return newError("index assignment not supported: %s", left.Type())
+    }
+}
+
+
+func isLogical(ie *ast.InfixExpression) bool {
+    return ie.Operator == "&&" || ie.Operator == "||"
+}
+
+// && and || only evaluate their right side when the left one does not decide the result. Either
+// way the result is the last side evaluated, so x || default works. This evaluates the left side
+// and reports whether it was the deciding one
+func evalLogicalLeft(ie *ast.InfixExpression, env *object.Environment) (object.Object, bool) {
+    left := Eval(ie.Left, env)
+    if isError(left) {
+        return left, true
+    }
+    if isTruthy(left) == (ie.Operator == "||") {
+        return left, true
+    }
+    return nil, false
+}
+
+
+func evalInterpolatedString(is *ast.InterpolatedString, env *object.Environment) object.Object {
+    var out bytes.Buffer
+    for _, part := range is.Parts {
+        val := Eval(part, env)
+        if isError(val) {
+            return val
+        }
+        out.WriteString(val.Inspect())
+    }
+    return &object.String{Value: out.String()}
 }
 
 
@@ -136,6 +344,8 @@ func evalIndexExpression(left, index object.Object) object.Object {
     switch {
     case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
         return evalArrayIndexExpression(left, index)
+    case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+        return evalStringIndexExpression(left, index)
     case left.Type() == object.HASH_OBJ:
         return evalHashIndexExpression(left, index)
     default:
@@ -146,11 +356,10 @@ func evalIndexExpression(left, index object.Object) object.Object {
 
 func evalHashIndexExpression(hash, index object.Object) object.Object {
     hashObject := hash.(*object.Hash)
-    key, ok := index.(object.Hashable)
-    if !ok {
+    if !object.IsHashable(index) {
         return newError("unusable as hash key: %s", index.Type())
     }
-    pair, ok := hashObject.Pairs[key.HashKey()]
+    pair, ok := hashObject.Get(index)
     if !ok {
         return NULL
     }
@@ -160,15 +369,181 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 
 func evalArrayIndexExpression(array, index object.Object) object.Object {
     arrayObject := array.(*object.Array)
-    idx := index.(*object.Integer).Value
-    max := int64(len(arrayObject.Elements) - 1)
-    if idx < 0 || idx > max {
-        return NULL
+    idx, ok := sequenceIndex(index.(*object.Integer), len(arrayObject.Elements))
+    if !ok {
+        return indexOutOfRange(index)
     }
     return arrayObject.Elements[idx]
 }
 
 
+// Strings are indexed by character, and give a string with just that one
+func evalStringIndexExpression(str, index object.Object) object.Object {
+    runes := []rune(str.(*object.String).Value)
+    idx, ok := sequenceIndex(index.(*object.Integer), len(runes))
+    if !ok {
+        return indexOutOfRange(index)
+    }
+    return &object.String{Value: string(runes[idx])}
+}
+
+
+// Where index points in something n long. Negative ones count from the end, so -1 is the last one.
+// ok is false if it is past either end
+func sequenceIndex(index *object.Integer, n int) (int, bool) {
+    if index.Big != nil {
+        return 0, false
+    }
+    idx := index.Value
+    if idx < 0 {
+        idx += int64(n)
+    }
+    if idx < 0 || idx >= int64(n) {
+        return 0, false
+    }
+    return int(idx), true
+}
+
+
+func indexOutOfRange(index object.Object) object.Object {
+    if StrictIndexing {
+        return newError("index out of range: %s", index.Inspect())
+    }
+    return NULL
+}
+
+
+func evalSliceExpression(se *ast.SliceExpression, env *object.Environment) object.Object {
+    left := Eval(se.Left, env)
+    if isError(left) {
+        return left
+    }
+    bounds := []object.Object{}
+    for _, exp := range []ast.Expression{se.Start, se.End, se.Step} {
+        if exp == nil {
+            bounds = append(bounds, nil)
+            continue
+        }
+        bound := Eval(exp, env)
+        if isError(bound) {
+            return bound
+        }
+        bounds = append(bounds, bound)
+    }
+
+    switch left := left.(type) {
+    case *object.Array:
+        indices, err := sliceIndices(len(left.Elements), bounds[0], bounds[1], bounds[2])
+        if err != nil {
+            return err
+        }
+        elements := make([]object.Object, len(indices))
+        for i, idx := range indices {
+            elements[i] = left.Elements[idx]
+        }
+        return &object.Array{Elements: elements}
+
+    case *object.String:
+        runes := []rune(left.Value)
+        indices, err := sliceIndices(len(runes), bounds[0], bounds[1], bounds[2])
+        if err != nil {
+            return err
+        }
+        sliced := make([]rune, len(indices))
+        for i, idx := range indices {
+            sliced[i] = runes[idx]
+        }
+        return &object.String{Value: string(sliced)}
+
+    default:
+        return newError("slice operator not supported: %s", left.Type())
+    }
+}
+
+
+// The indices a slice of something n long picks out, in order. Like Python, bounds that are left
+// out (nil) cover everything in the direction of step, negative ones count from the end, and ones
+// past either end are cut down to fit instead of being an error
+func sliceIndices(n int, start, end, step object.Object) ([]int, *object.Error) {
+    stepVal := int64(1)
+    if step != nil {
+        var err *object.Error
+        if stepVal, err = sliceBound(step); err != nil {
+            return nil, err
+        }
+        if stepVal == 0 {
+            return nil, newError("slice step must not be zero")
+        }
+    }
+
+    length := int64(n)
+    // where a bound stops when it is left out or past the end. -1 is just before the first element
+    first, last := int64(0), length
+    if stepVal < 0 {
+        first, last = length - 1, -1
+    }
+    clamp := func(bound object.Object, missing int64) (int64, *object.Error) {
+        if bound == nil {
+            return missing, nil
+        }
+        val, err := sliceBound(bound)
+        if err != nil {
+            return 0, err
+        }
+        if val < 0 {
+            val += length
+        }
+        switch {
+        case val < 0 && stepVal < 0:
+            return -1, nil
+        case val < 0:
+            return 0, nil
+        case val >= length && stepVal < 0:
+            return length - 1, nil
+        case val >= length:
+            return length, nil
+        }
+        return val, nil
+    }
+
+    from, err := clamp(start, first)
+    if err != nil {
+        return nil, err
+    }
+    to, err := clamp(end, last)
+    if err != nil {
+        return nil, err
+    }
+
+    indices := []int{}
+    for i := from; (stepVal > 0 && i < to) || (stepVal < 0 && i > to); i += stepVal {
+        indices = append(indices, int(i))
+        // a huge step would overflow i
+        if (stepVal > 0 && to - i <= stepVal) || (stepVal < 0 && to - i >= stepVal) {
+            break
+        }
+    }
+    return indices, nil
+}
+
+
+// Integers too big for an int64 are past either end anyway, so they are cut down to the biggest
+// or smallest int64
+func sliceBound(bound object.Object) (int64, *object.Error) {
+    integer, ok := bound.(*object.Integer)
+    if !ok {
+        return 0, newError("slice index must be INTEGER, got=%s", bound.Type())
+    }
+    if integer.Big != nil {
+        if integer.Big.Sign() < 0 {
+            return math.MinInt64, nil
+        }
+        return math.MaxInt64, nil
+    }
+    return integer.Value, nil
+}
+
+
 func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
     var result []object.Object
     for _, e := range exps {
@@ -198,9 +573,11 @@ func evalProgram(stmts []ast.Statement, env *object.Environment) object.Object {
         result = Eval(statement, env)
         switch result := result.(type) {
         case *object.ReturnValue:
-            return result.Value
+            return resolveTailCall(result.Value, env)
         case *object.Error:
             return result
+        case *object.Break, *object.Continue:
+            return newError("%s outside of a loop", result.Inspect())
         }
     }
     return result
@@ -221,6 +598,8 @@ func evalPrefixExpression(operator string, right object.Object, env *object.Envi
         return evalBangOperatorExpression(right, env)
     case "-":
         return evalMinusPrefixOperatorExpression(right, env)
+    case "~":
+        return evalBitNotPrefixOperatorExpression(right, env)
     default:
         return newError("unknown operator: %s%s", operator, right.Type())
     }
@@ -242,11 +621,35 @@ func evalBangOperatorExpression(right object.Object, env *object.Environment) ob
 
 
 func evalMinusPrefixOperatorExpression(right object.Object, env *object.Environment) object.Object {
-    if right.Type() != object.INTEGER_OBJ {
+    switch right := right.(type) {
+    case *object.Integer:
+        if right.Big != nil {
+            return object.NewBigInteger(new(big.Int).Neg(right.Big))
+        }
+        if right.Value == math.MinInt64 {
+            if CheckedArithmetic {
+                return newError("integer overflow: -%d", right.Value)
+            }
+            return object.NewBigInteger(new(big.Int).Neg(right.BigValue()))
+        }
+        return &object.Integer{Value: -right.Value}
+    case *object.Float:
+        return &object.Float{Value: -right.Value}
+    default:
         return newError("unknown operator: -%s", right.Type())
     }
-    value := right.(*object.Integer).Value
-    return &object.Integer{Value: -value}
+}
+
+
+func evalBitNotPrefixOperatorExpression(right object.Object, env *object.Environment) object.Object {
+    integer, ok := right.(*object.Integer)
+    if !ok {
+        return newError("unknown operator: ~%s", right.Type())
+    }
+    if integer.Big != nil {
+        return object.NewBigInteger(new(big.Int).Not(integer.Big))
+    }
+    return &object.Integer{Value: ^integer.Value}
 }
 
 
@@ -254,12 +657,15 @@ func evalInfixExpression(operator string, left, right object.Object, env *object
     switch {
     case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
         return evalIntegerInfixExpression(operator, left, right)
+    case isNumber(left) && isNumber(right):
+        // at least one of them is a float, so the integer gets promoted
+        return evalFloatInfixExpression(operator, left, right)
     case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
         return evalStringInfixExpression(operator, left, right)
     case operator == "==":
-        return nativeBoolToBooleanObject(left == right)
+        return nativeBoolToBooleanObject(object.Equal(left, right))
     case operator == "!=":
-        return nativeBoolToBooleanObject(left != right)
+        return nativeBoolToBooleanObject(!object.Equal(left, right))
     case left.Type() != right.Type():
         return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
     default:
@@ -269,22 +675,84 @@ func evalInfixExpression(operator string, left, right object.Object, env *object
 
 
 func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
-    leftVal := left.(*object.Integer).Value
-    rightVal := right.(*object.Integer).Value
+    leftInt := left.(*object.Integer)
+    rightInt := right.(*object.Integer)
+    if leftInt.Big != nil || rightInt.Big != nil {
+        return evalBigIntegerInfixExpression(operator, leftInt.BigValue(), rightInt.BigValue())
+    }
+    leftVal := leftInt.Value
+    rightVal := rightInt.Value
 
     switch operator {
     case "+":
-        return &object.Integer{Value: leftVal + rightVal}
+        result := leftVal + rightVal
+        if addOverflows(leftVal, rightVal, result) {
+            return integerOverflow(leftVal, operator, rightVal)
+        }
+        return &object.Integer{Value: result}
     case "-":
-        return &object.Integer{Value: leftVal - rightVal}
+        result := leftVal - rightVal
+        if subOverflows(leftVal, rightVal, result) {
+            return integerOverflow(leftVal, operator, rightVal)
+        }
+        return &object.Integer{Value: result}
     case "*":
-        return &object.Integer{Value: leftVal * rightVal}
+        result := leftVal * rightVal
+        if mulOverflows(leftVal, rightVal, result) {
+            return integerOverflow(leftVal, operator, rightVal)
+        }
+        return &object.Integer{Value: result}
     case "/":
+        if rightVal == 0 {
+            return newError("division by zero")
+        }
+        if leftVal == math.MinInt64 && rightVal == -1 {
+            return integerOverflow(leftVal, operator, rightVal)
+        }
         return &object.Integer{Value: leftVal / rightVal}
+    case "%":
+        if rightVal == 0 {
+            return newError("modulo by zero")
+        }
+        return &object.Integer{Value: leftVal % rightVal}
+    case "**":
+        if rightVal < 0 {
+            return &object.Float{Value: math.Pow(float64(leftVal), float64(rightVal))}
+        }
+        result, ok := powInt64(leftVal, rightVal)
+        if !ok {
+            return integerOverflow(leftVal, operator, rightVal)
+        }
+        return &object.Integer{Value: result}
+    case "&":
+        return &object.Integer{Value: leftVal & rightVal}
+    case "|":
+        return &object.Integer{Value: leftVal | rightVal}
+    case "^":
+        return &object.Integer{Value: leftVal ^ rightVal}
+    case "<<":
+        if rightVal < 0 {
+            return newError("negative shift count: %d", rightVal)
+        }
+        // shifting by 64 or more gives 0, which only fits if leftVal was 0 anyway
+        result := leftVal << rightVal
+        if result >> rightVal != leftVal {
+            return integerOverflow(leftVal, operator, rightVal)
+        }
+        return &object.Integer{Value: result}
+    case ">>":
+        if rightVal < 0 {
+            return newError("negative shift count: %d", rightVal)
+        }
+        return &object.Integer{Value: leftVal >> rightVal}
     case "<":
         return nativeBoolToBooleanObject(leftVal < rightVal)
     case ">":
         return nativeBoolToBooleanObject(leftVal > rightVal)
+    case "<=":
+        return nativeBoolToBooleanObject(leftVal <= rightVal)
+    case ">=":
+        return nativeBoolToBooleanObject(leftVal >= rightVal)
     case "==":
         return nativeBoolToBooleanObject(leftVal == rightVal)
     case "!=":
@@ -295,13 +763,238 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 }
 
 
-func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
-    if operator != "+" {
+func addOverflows(left, right, result int64) bool {
+    // overflow can only happen when both have the same sign, and then flips the sign of the result
+    return (left >= 0) == (right >= 0) && (result >= 0) != (left >= 0)
+}
+
+
+func subOverflows(left, right, result int64) bool {
+    return (left >= 0) != (right >= 0) && (result >= 0) != (left >= 0)
+}
+
+
+func mulOverflows(left, right, result int64) bool {
+    if left == 0 || right == 0 {
+        return false
+    }
+    if (left == -1 && right == math.MinInt64) || (right == -1 && left == math.MinInt64) {
+        return true
+    }
+    return result / right != left
+}
+
+
+// base ** exp by squaring, exp >= 0. Reports false if the result does not fit in an int64
+func powInt64(base, exp int64) (int64, bool) {
+    result := int64(1)
+    for exp > 0 {
+        if exp & 1 == 1 {
+            product := result * base
+            if mulOverflows(result, base, product) {
+                return 0, false
+            }
+            result = product
+        }
+        exp >>= 1
+        if exp > 0 {
+            square := base * base
+            if mulOverflows(base, base, square) {
+                return 0, false
+            }
+            base = square
+        }
+    }
+    return result, true
+}
+
+
+// The result of left operator right did not fit in an int64
+func integerOverflow(left int64, operator string, right int64) object.Object {
+    if CheckedArithmetic {
+        return newError("integer overflow: %d %s %d", left, operator, right)
+    }
+    return evalBigIntegerInfixExpression(operator, big.NewInt(left), big.NewInt(right))
+}
+
+
+func evalBigIntegerInfixExpression(operator string, leftVal, rightVal *big.Int) object.Object {
+    switch operator {
+    case "+":
+        return object.NewBigInteger(new(big.Int).Add(leftVal, rightVal))
+    case "-":
+        return object.NewBigInteger(new(big.Int).Sub(leftVal, rightVal))
+    case "*":
+        return object.NewBigInteger(new(big.Int).Mul(leftVal, rightVal))
+    case "/":
+        if rightVal.Sign() == 0 {
+            return newError("division by zero")
+        }
+        // Quo and Rem truncate towards zero like int64 / and % do, Div and Mod would not
+        return object.NewBigInteger(new(big.Int).Quo(leftVal, rightVal))
+    case "%":
+        if rightVal.Sign() == 0 {
+            return newError("modulo by zero")
+        }
+        return object.NewBigInteger(new(big.Int).Rem(leftVal, rightVal))
+    case "**":
+        if rightVal.Sign() < 0 {
+            f, _ := new(big.Float).SetInt(rightVal).Float64()
+            base, _ := new(big.Float).SetInt(leftVal).Float64()
+            return &object.Float{Value: math.Pow(base, f)}
+        }
+        if !rightVal.IsInt64() {
+            return newError("exponent too large: %s", rightVal)
+        }
+        // 0, 1 and -1 stay small, anything else has at least this many bits more for each power
+        if bits := int64(new(big.Int).Abs(leftVal).BitLen() - 1); bits > 0 && rightVal.Int64() > int64(MaxIntegerBits) / bits {
+            return integerTooLarge(operator)
+        }
+        return object.NewBigInteger(new(big.Int).Exp(leftVal, rightVal, nil))
+    case "&":
+        return object.NewBigInteger(new(big.Int).And(leftVal, rightVal))
+    case "|":
+        return object.NewBigInteger(new(big.Int).Or(leftVal, rightVal))
+    case "^":
+        return object.NewBigInteger(new(big.Int).Xor(leftVal, rightVal))
+    case "<<", ">>":
+        if rightVal.Sign() < 0 {
+            return newError("negative shift count: %s", rightVal)
+        }
+        if !rightVal.IsInt64() {
+            return newError("shift count too large: %s", rightVal)
+        }
+        if operator == "<<" {
+            if leftVal.Sign() != 0 && rightVal.Int64() > int64(MaxIntegerBits - leftVal.BitLen()) {
+                return integerTooLarge(operator)
+            }
+            return object.NewBigInteger(new(big.Int).Lsh(leftVal, uint(rightVal.Int64())))
+        }
+        return object.NewBigInteger(new(big.Int).Rsh(leftVal, uint(rightVal.Int64())))
+    case "<":
+        return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+    case ">":
+        return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+    case "<=":
+        return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) <= 0)
+    case ">=":
+        return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) >= 0)
+    case "==":
+        return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+    case "!=":
+        return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+    default:
+        return newError("unknown operator: %s %s %s", object.INTEGER_OBJ, operator, object.INTEGER_OBJ)
+    }
+}
+
+
+func integerTooLarge(operator string) object.Object {
+    return newError("result too large: %s would make an integer of more than %d bits", operator, MaxIntegerBits)
+}
+
+
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+    leftVal := toFloat(left)
+    rightVal := toFloat(right)
+
+    switch operator {
+    case "+":
+        return &object.Float{Value: leftVal + rightVal}
+    case "-":
+        return &object.Float{Value: leftVal - rightVal}
+    case "*":
+        return &object.Float{Value: leftVal * rightVal}
+    case "/":
+        if rightVal == 0 {
+            return newError("division by zero")
+        }
+        return &object.Float{Value: leftVal / rightVal}
+    case "%":
+        if rightVal == 0 {
+            return newError("modulo by zero")
+        }
+        return &object.Float{Value: math.Mod(leftVal, rightVal)}
+    case "**":
+        return &object.Float{Value: math.Pow(leftVal, rightVal)}
+    case "<", ">", "<=", ">=", "==", "!=":
+        // on the original values, so an integer is not rounded to a float first, and == agrees
+        // with object.Equal
+        cmp, ok := object.CompareNumbers(left, right)
+        return nativeBoolToBooleanObject(comparisonHolds(operator, cmp, ok))
+    default:
         return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
     }
+}
+
+
+// Whether left operator right is true, given cmp and ok from object.CompareNumbers. NaN is not
+// less than, greater than or equal to anything
+func comparisonHolds(operator string, cmp int, ok bool) bool {
+    if !ok {
+        return operator == "!="
+    }
+    switch operator {
+    case "<":
+        return cmp < 0
+    case ">":
+        return cmp > 0
+    case "<=":
+        return cmp <= 0
+    case ">=":
+        return cmp >= 0
+    case "==":
+        return cmp == 0
+    default:
+        return cmp != 0
+    }
+}
+
+
+func isNumber(obj object.Object) bool {
+    return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+
+func toFloat(obj object.Object) float64 {
+    switch obj := obj.(type) {
+    case *object.Integer:
+        if obj.Big != nil {
+            f, _ := new(big.Float).SetInt(obj.Big).Float64()
+            return f
+        }
+        return float64(obj.Value)
+    case *object.Float:
+        return obj.Value
+    default:
+        return 0
+    }
+}
+
+
+// Strings compare byte by byte, which for UTF-8 is the same as comparing code points
+func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
     leftVal := left.(*object.String).Value
     rightVal := right.(*object.String).Value
-    return &object.String{Value: leftVal + rightVal}
+
+    switch operator {
+    case "+":
+        return &object.String{Value: leftVal + rightVal}
+    case "<":
+        return nativeBoolToBooleanObject(leftVal < rightVal)
+    case ">":
+        return nativeBoolToBooleanObject(leftVal > rightVal)
+    case "<=":
+        return nativeBoolToBooleanObject(leftVal <= rightVal)
+    case ">=":
+        return nativeBoolToBooleanObject(leftVal >= rightVal)
+    case "==":
+        return nativeBoolToBooleanObject(leftVal == rightVal)
+    case "!=":
+        return nativeBoolToBooleanObject(leftVal != rightVal)
+    default:
+        return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+    }
 }
 
 
@@ -320,6 +1013,75 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 }
 
 
+func evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+    // a return f() inside the try must make its call here, or the try can't catch what it throws
+    result := resolveReturnedTailCall(Eval(te.Block, env), env)
+
+    if errObj, ok := result.(*object.Error); ok && te.Catch != nil {
+        catchEnv := env
+        if te.Param != nil {
+            catchEnv = object.NewEnclosedEnvironment(env)
+            catchEnv.Set(te.Param.Value, caughtErrorValue(errObj))
+        }
+        result = resolveReturnedTailCall(Eval(te.Catch, catchEnv), env)
+    }
+
+    if te.Finally != nil {
+        // finally only replaces the result if it leaves the try itself, by returning or failing
+        finally := resolveReturnedTailCall(Eval(te.Finally, env), env)
+        if leavesBlock(finally) {
+            return finally
+        }
+    }
+
+    return result
+}
+
+
+// What a catch block gets to see of an error. It can't be the Error itself, that would just
+// be raised again as soon as it is evaluated
+func caughtErrorValue(err *object.Error) object.Object {
+    trace := []object.Object{}
+    for _, line := range err.TraceLines() {
+        trace = append(trace, &object.String{Value: line})
+    }
+
+    hash := object.NewHash()
+    set := func(key string, value object.Object) {
+        hash.Set(&object.String{Value: key}, value)
+    }
+    set("message", &object.String{Value: err.Message})
+    set("line", &object.Integer{Value: int64(err.Pos.Line)})
+    set("column", &object.Integer{Value: int64(err.Pos.Column)})
+    set("trace", &object.Array{Elements: trace})
+    hash.Caught = err
+    return hash
+}
+
+
+// Turns the value of a throw, or the argument of error(), into an error. A caught error can be
+// thrown again as it is, and still points at where it first happened
+func errorFromValue(val object.Object) *object.Error {
+    switch val := val.(type) {
+    case *object.String:
+        return newError("%s", val.Value)
+    case *object.Hash:
+        if pair, ok := val.Get(&object.String{Value: "message"}); ok {
+            if message, ok := pair.Value.(*object.String); ok {
+                err := newError("%s", message.Value)
+                if val.Caught != nil {
+                    err.Pos = val.Caught.Pos
+                    // not nil even if it is empty, or annotateError would fill in where it is thrown
+                    err.Trace = append([]object.Frame{}, val.Caught.Trace...)
+                }
+                return err
+            }
+        }
+    }
+    return newError("%s", val.Inspect())
+}
+
+
 func isTruthy(obj object.Object) bool {
     switch obj {
     case NULL:
@@ -340,15 +1102,123 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
     for _, statement := range block.Statements {
         result = Eval(statement, env)
 
-        if result != nil {
-            rt := result.Type()
-            if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+        if leavesBlock(result) {
+            return result
+        }
+    }
+
+    return result
+}
+
+
+// Reports whether result stops the rest of a block from running: a return, break, continue or error
+func leavesBlock(result object.Object) bool {
+    if result == nil {
+        return false
+    }
+    switch result.Type() {
+    case object.RETURN_VALUE_OBJ, object.ERROR_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+        return true
+    }
+    return false
+}
+
+
+func evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
+    for {
+        condition := Eval(ws.Condition, env)
+        if isError(condition) {
+            return condition
+        }
+        if !isTruthy(condition) {
+            return NULL
+        }
+        if result, done := evalLoopBody(ws.Body, env); done {
+            return result
+        }
+    }
+}
+
+
+// Runs one pass of a loop. Reports whether the loop is done, and if so what the loop statement
+// evaluates to
+func evalLoopBody(body *ast.BlockStatement, env *object.Environment) (object.Object, bool) {
+    result := Eval(body, env)
+    switch result {
+    case BREAK:
+        return NULL, true
+    case CONTINUE:
+        return nil, false
+    }
+    if leavesBlock(result) {
+        return result, true
+    }
+    return nil, false
+}
+
+
+func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+    iterable := Eval(fs.Iterable, env)
+    if isError(iterable) {
+        return iterable
+    }
+
+    // Every pass gets its own environment, so closures made in the body keep the values they saw
+    pass := func(key, value object.Object) (object.Object, bool) {
+        loopEnv := object.NewEnclosedEnvironment(env)
+        if fs.Value == nil {
+            loopEnv.Set(fs.Key.Value, value)
+        } else {
+            loopEnv.Set(fs.Key.Value, key)
+            loopEnv.Set(fs.Value.Value, value)
+        }
+        return evalLoopBody(fs.Body, loopEnv)
+    }
+
+    switch iterable := iterable.(type) {
+    case *object.Array:
+        for i, element := range iterable.Elements {
+            if result, done := pass(&object.Integer{Value: int64(i)}, element); done {
                 return result
             }
         }
+
+    case *object.Hash:
+        for _, pair := range iterable.OrderedPairs() {
+            // for (k in hash) goes over the keys
+            value := pair.Value
+            if fs.Value == nil {
+                value = pair.Key
+            }
+            if result, done := pass(pair.Key, value); done {
+                return result
+            }
+        }
+
+    case *object.String:
+        i := int64(0)
+        for _, ch := range iterable.Value {
+            if result, done := pass(&object.Integer{Value: i}, &object.String{Value: string(ch)}); done {
+                return result
+            }
+            i += 1
+        }
+
+    case *object.Range:
+        n := iterable.Len()
+        value := iterable.Start
+        for i := uint64(0); i < n; i++ {
+            if result, done := pass(&object.Integer{Value: int64(i)}, &object.Integer{Value: value}); done {
+                return result
+            }
+            value += iterable.Step
+        }
+
+    default:
+        return newError("cannot iterate over %s", iterable.Type())
     }
 
-    return result
+    return NULL
 }
 
 
@@ -365,12 +1235,38 @@ func isError(obj object.Object) bool {
 }
 
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+// pos is where the call is made, env is the environment it is made from
+func applyFunction(fn object.Object, args []object.Object, pos token.Position, env *object.Environment) object.Object {
     switch fn := fn.(type){
     case *object.Function:
-        extendedEnv := extendFunctionEnv(fn, args)
-        evaluated := Eval(fn.Body, extendedEnv)
-        return unwrapReturnValue(evaluated)
+        calls := env.CallStack()
+        if calls.Depth() >= MaxCallDepth {
+            return newError("maximum recursion depth exceeded")
+        }
+        calls.Push(object.Frame{Function: fn.Name, Pos: pos})
+
+        // Tail calls come back as TailCall objects instead of being made, and we loop to make them.
+        // That way a recursive loop runs in constant Go stack
+        for {
+            if len(args) != len(fn.Parameters) {
+                calls.Pop()
+                return newError("wrong number of arguments. got=%d, want=%d", len(args), len(fn.Parameters))
+            }
+            extendedEnv := extendFunctionEnv(fn, args)
+            evaluated := unwrapReturnValue(evalTailBlock(fn.Body, extendedEnv))
+            if evaluated == BREAK || evaluated == CONTINUE {
+                calls.Pop()
+                return newError("%s outside of a loop", evaluated.Inspect())
+            }
+
+            tailCall, ok := evaluated.(*object.TailCall)
+            if !ok {
+                calls.Pop()
+                return evaluated
+            }
+            fn, args = tailCall.Fn, tailCall.Args
+            calls.TailCall(fn.Name)
+        }
     case *object.Builtin:
         return fn.Fn(args...)
     default:
@@ -388,6 +1284,90 @@ func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Enviro
 }
 
 
+// Like evalBlockStatement, but the last statement is in tail position
+func evalTailBlock(block *ast.BlockStatement, env *object.Environment) object.Object {
+    var result object.Object
+
+    for i, statement := range block.Statements {
+        if es, ok := statement.(*ast.ExpressionStatement); ok && i == len(block.Statements)-1 {
+            return annotateError(evalTailExpression(es.Expression, env), es, env)
+        }
+        result = Eval(statement, env)
+
+        if leavesBlock(result) {
+            return result
+        }
+    }
+
+    return result
+}
+
+
+// Evaluates an expression whose value is the value of the function it is in. If that is a call to
+// a function, the call is handed back as a TailCall for applyFunction to make
+func evalTailExpression(exp ast.Expression, env *object.Environment) object.Object {
+    switch exp := exp.(type) {
+    case *ast.CallExpression:
+        function := Eval(exp.Function, env)
+        if isError(function) {
+            return function
+        }
+        args := evalExpressions(exp.Arguments, env)
+        if len(args) == 1 && isError(args[0]) {
+            return args[0]
+        }
+        if fn, ok := function.(*object.Function); ok {
+            return &object.TailCall{Fn: fn, Args: args, Pos: exp.Pos()}
+        }
+        return annotateError(applyFunction(function, args, exp.Pos(), env), exp, env)
+
+    case *ast.InfixExpression:
+        if !isLogical(exp) {
+            return Eval(exp, env)
+        }
+        if left, done := evalLogicalLeft(exp, env); done {
+            return left
+        }
+        return evalTailExpression(exp.Right, env)
+
+    case *ast.IfExpression:
+        condition := Eval(exp.Condition, env)
+        if isError(condition) {
+            return condition
+        }
+        if isTruthy(condition) {
+            return evalTailBlock(exp.Consequence, env)
+        } else if exp.Alternative != nil {
+            return evalTailBlock(exp.Alternative, env)
+        } else {
+            return NULL
+        }
+
+    default:
+        return Eval(exp, env)
+    }
+}
+
+
+// Makes the call if obj is a TailCall, for places that need the actual value
+func resolveTailCall(obj object.Object, env *object.Environment) object.Object {
+    if tailCall, ok := obj.(*object.TailCall); ok {
+        return applyFunction(tailCall.Fn, tailCall.Args, tailCall.Pos, env)
+    }
+    return obj
+}
+
+
+func resolveReturnedTailCall(obj object.Object, env *object.Environment) object.Object {
+    if returnValue, ok := obj.(*object.ReturnValue); ok {
+        if _, ok := returnValue.Value.(*object.TailCall); ok {
+            return &object.ReturnValue{Value: resolveTailCall(returnValue.Value, env)}
+        }
+    }
+    return obj
+}
+
+
 func unwrapReturnValue(obj object.Object) object.Object {
     if returnValue, ok := obj.(*object.ReturnValue); ok {
         return returnValue.Value
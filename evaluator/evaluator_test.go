@@ -27,6 +27,11 @@ func TestEvalIntegerExpression(t *testing.T) {
         {"3 * 3 * 3 + 10", 37},
         {"3 * (3 * 3) + 10", 37},
         {"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+        {"0xff + 0b1 + 0o10", 264},
+        {"1_000 * 1_000", 1000000},
+        {"10 % 3", 1},
+        {"-10 % 3", -1},
+        {"2 + 10 % 4 * 3", 8},
     }
     for _, tt := range tests {
         evaluated := testEval(tt.input)
@@ -34,6 +39,191 @@ func TestEvalIntegerExpression(t *testing.T) {
     }
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+    tests := []struct {
+        input string
+        expected float64
+    }{
+        {"2.5", 2.5},
+        {"-2.5", -2.5},
+        {"1.5 + 1.5", 3},
+        {"1 + 0.5", 1.5},
+        {"0.5 + 1", 1.5},
+        {"10 / 4.0", 2.5},
+        {"3 * 1.5 - 1", 3.5},
+        {"1e3 / 10", 100},
+        {"(1 + 2) * 0.5", 1.5},
+    }
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+        testFloatObject(t, evaluated, tt.expected)
+    }
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+    result, ok := obj.(*object.Float)
+    if !ok {
+        t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+        return false
+    }
+    if result.Value != expected {
+        t.Errorf("object has wrong value. got=%g, want=%g", result.Value, expected)
+        return false
+    }
+    return true
+}
+
+func TestCheckedArithmetic(t *testing.T) {
+    CheckedArithmetic = true
+    defer func() { CheckedArithmetic = false }()
+
+    tests := []struct {
+        input    string
+        expected interface{}
+    }{
+        {"9223372036854775807 + 1", "integer overflow: 9223372036854775807 + 1"},
+        {"-9223372036854775807 - 2", "integer overflow: -9223372036854775807 - 2"},
+        {"4611686018427387904 * 2", "integer overflow: 4611686018427387904 * 2"},
+        {"let min = -9223372036854775807 - 1; min / -1", "integer overflow: -9223372036854775808 / -1"},
+        {"let min = -9223372036854775807 - 1; -min", "integer overflow: --9223372036854775808"},
+        {"9223372036854775806 + 1", 9223372036854775807},
+        {"-4611686018427387904 * 2", -9223372036854775808},
+        {"3037000499 * 3037000499", 9223372030926249001},
+    }
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+        switch expected := tt.expected.(type) {
+        case int:
+            testIntegerObject(t, evaluated, int64(expected))
+        case string:
+            errObj, ok := evaluated.(*object.Error)
+            if !ok {
+                t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+                continue
+            }
+            if errObj.Message != expected {
+                t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+            }
+        }
+    }
+}
+
+func TestBigIntegers(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected string
+    }{
+        {"9223372036854775807 + 1", "9223372036854775808"},
+        {"-9223372036854775807 - 2", "-9223372036854775809"},
+        {"9223372036854775807 * 9223372036854775807", "85070591730234615847396907784232501249"},
+        {"let min = -9223372036854775807 - 1; min / -1", "9223372036854775808"},
+        {"let min = -9223372036854775807 - 1; -min", "9223372036854775808"},
+        {
+            `let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(30)`,
+            "265252859812191058636308480000000",
+        },
+        // results that fit again go back to being plain integers
+        {"(9223372036854775807 + 10) - 10", "9223372036854775807"},
+        {"(9223372036854775807 * 4) / 4", "9223372036854775807"},
+        {"(9223372036854775807 * 3) % 2", "1"},
+        {"-(9223372036854775807 * 2)", "-18446744073709551614"},
+        {"100000000000000000000", "100000000000000000000"},
+        {"0xffffffffffffffff", "18446744073709551615"},
+        {"-9223372036854775809", "-9223372036854775809"},
+        {"100000000000000000000 / 10", "10000000000000000000"},
+    }
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+        integer, ok := evaluated.(*object.Integer)
+        if !ok {
+            t.Errorf("%q: object is not Integer. got=%T (%+v)", tt.input, evaluated, evaluated)
+            continue
+        }
+        if integer.Inspect() != tt.expected {
+            t.Errorf("%q: wrong value. got=%s, want=%s", tt.input, integer.Inspect(), tt.expected)
+        }
+    }
+
+    testIntegerObject(t, testEval("(9223372036854775807 + 1) - 1"), 9223372036854775807)
+    // 9223372036854775808 is too big on its own, but negated fits again
+    testIntegerObject(t, testEval("-9223372036854775808"), -9223372036854775808)
+    testIntegerObject(t, testEval("100000000000000000000 - 99999999999999999999"), 1)
+
+    // what Inspect prints for a big integer reads back as the same value
+    for _, input := range []string{"2 ** 100", "-(3 ** 50)", "0xffffffffffffffff"} {
+        printed := testEval(input).Inspect()
+        if again := testEval(printed); again.Inspect() != printed {
+            t.Errorf("%q: %s does not read back, got %s", input, printed, again.Inspect())
+        }
+    }
+
+    booleans := []struct {
+        input    string
+        expected bool
+    }{
+        {"9223372036854775807 + 1 > 9223372036854775807", true},
+        {"9223372036854775807 + 1 == 9223372036854775807 + 1", true},
+        {"9223372036854775807 + 1 != 9223372036854775807 + 2", true},
+        {"-(9223372036854775807 * 2) < 0", true},
+        {"(9223372036854775807 + 1) * 1.0 > 9.2e18", true},
+    }
+    for _, tt := range booleans {
+        testBooleanObject(t, testEval(tt.input), tt.expected)
+    }
+}
+
+func TestBigIntegerHashKeys(t *testing.T) {
+    input := `let big = 9223372036854775807 * 2; let h = {big: "big", 1: "one"}; h[9223372036854775807 + 9223372036854775807]`
+    evaluated := testEval(input)
+    str, ok := evaluated.(*object.String)
+    if !ok {
+        t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+    }
+    if str.Value != "big" {
+        t.Errorf("wrong value. got=%q", str.Value)
+    }
+}
+
+func TestFloatModulo(t *testing.T) {
+    testFloatObject(t, testEval("5.5 % 2"), 1.5)
+}
+
+func TestMixedNumberComparison(t *testing.T) {
+    tests := []struct {
+        input   string
+        expected bool
+    }{
+        {"1 == 1.0", true},
+        {"1.0 != 1", false},
+        {"1 < 1.5", true},
+        {"2.5 > 3", false},
+        {"0.1 + 0.2 == 0.3", false},
+        {"7 / 2 == 3", true},
+    }
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+        testBooleanObject(t, evaluated, tt.expected)
+    }
+}
+
+func TestFloatInspect(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected string
+    }{
+        {"2.0", "2.0"},
+        {"1.5 * 2", "3.0"},
+        {"0.1 + 0.2", "0.30000000000000004"},
+        {"1e21 * 10", "1e+22"},
+    }
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+        if evaluated.Inspect() != tt.expected {
+            t.Errorf("wrong Inspect for %q. got=%q, want=%q", tt.input, evaluated.Inspect(), tt.expected)
+        }
+    }
+}
+
 func testEval(input string) object.Object {
     l := lexer.New(input)
     p := parser.New(l)
@@ -48,6 +238,10 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
         t.Errorf("object is not Integer. got=%T (%+v)", obj, obj)
         return false
     }
+    if result.Big != nil {
+        t.Errorf("object is a big Integer. got=%s, want=%d", result.Big, expected)
+        return false
+    }
     if result.Value != expected {
         t.Errorf("object has wrong value. got=%d, want=%d",
         result.Value, expected)
@@ -231,6 +425,26 @@ func TestErrorHandling(t *testing.T) {
             `"Hello" - "World"`,
             "unknown operator: STRING - STRING",
         },
+        {
+            "1.5 + true",
+            "type mismatch: FLOAT + BOOLEAN",
+        },
+        {
+            "1 / 0",
+            "division by zero",
+        },
+        {
+            "let zero = 5 - 5; 10 % zero",
+            "modulo by zero",
+        },
+        {
+            "1.5 / 0",
+            "division by zero",
+        },
+        {
+            "1.5 % 0.0",
+            "modulo by zero",
+        },
         {
             `{"name": "aichacha"}[fn(x) { x }];`,
             "unusable as hash key: FUNCTION",
@@ -250,6 +464,741 @@ func TestErrorHandling(t *testing.T) {
 }
 
 
+func TestErrorPositions(t *testing.T) {
+    tests := []struct {
+        input           string
+        expectedInspect string
+    }{
+        {
+            "5 + true;",
+            "ERROR: 1:1: type mismatch: INTEGER + BOOLEAN",
+        },
+        {
+            "let x = 5;\nlet y = -true;",
+            "ERROR: 2:9: unknown operator: -BOOLEAN",
+        },
+        {
+            "let f = fn(x) {\n  x + foobar\n};\nf(1);",
+            "ERROR: 2:7: identifier not found: foobar",
+        },
+        {
+            "\n  len(1, 2)",
+            "ERROR: 2:3: wrong number of arguments. got=2, want=1",
+        },
+    }
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+        errObj, ok := evaluated.(*object.Error)
+        if !ok {
+            t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+            continue
+        }
+        if errObj.Inspect() != tt.expectedInspect {
+            t.Errorf("wrong error. expected=%q, got=%q", tt.expectedInspect, errObj.Inspect())
+        }
+    }
+}
+
+
+func TestErrorTraces(t *testing.T) {
+    input := `let inner = fn(x) {
+    x + true
+};
+let outer = fn(x) {
+    inner(x)
+};
+let wrap = fn() { fn() { outer(1) }() };
+wrap();`
+
+    evaluated := testEval(input)
+    errObj, ok := evaluated.(*object.Error)
+    if !ok {
+        t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+    }
+
+    expected := []struct {
+        function string
+        line     int
+        column   int
+    }{
+        // every call after wrap() is a tail call, so they all share its frame
+        {"inner", 8, 1},
+    }
+    if len(errObj.Trace) != len(expected) {
+        t.Fatalf("wrong trace length. expected=%d, got=%d (%+v)", len(expected), len(errObj.Trace), errObj.Trace)
+    }
+    for i, tt := range expected {
+        frame := errObj.Trace[i]
+        if frame.Function != tt.function || frame.Pos.Line != tt.line || frame.Pos.Column != tt.column {
+            t.Errorf("trace[%d] wrong. expected=%s at %d:%d, got=%s at %s", i, tt.function, tt.line, tt.column,
+                frame.Function, frame.Pos)
+        }
+    }
+
+    expectedTrace := `    at inner (2:5) [3 tail calls elided]
+    at <main> (8:1)
+`
+    if errObj.StackTrace() != expectedTrace {
+        t.Errorf("wrong stack trace.\nexpected=%q\ngot=     %q", expectedTrace, errObj.StackTrace())
+    }
+}
+
+
+func TestErrorTraceOutsideFunctions(t *testing.T) {
+    evaluated := testEval("let f = fn() { 1 }; f(); 1 + true")
+    errObj, ok := evaluated.(*object.Error)
+    if !ok {
+        t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+    }
+    if len(errObj.Trace) != 0 {
+        t.Errorf("expected no trace, got=%+v", errObj.Trace)
+    }
+    if errObj.StackTrace() != "" {
+        t.Errorf("expected no stack trace, got=%q", errObj.StackTrace())
+    }
+}
+
+
+func TestTryCatch(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected interface{}
+    }{
+        {"try { 1 } catch (e) { 2 }", 1},
+        {"try { 1 + true; 1 } catch (e) { 2 }", 2},
+        {`try { throw "bad" } catch (e) { e["message"] }`, "bad"},
+        {`try { error("bad") } catch (e) { e["message"] }`, "bad"},
+        {`try { 1 + true } catch (e) { e["message"] }`, "type mismatch: INTEGER + BOOLEAN"},
+        {"try { 1 / 0 } catch { 5 }", 5},
+        {"let x = try { 1 / 0 } catch (e) { -1 }; x", -1},
+        {`try { throw [1, 2] } catch (e) { e["message"] }`, "[1, 2]"},
+        {"try {\n  throw \"x\"\n} catch (e) { e[\"line\"] * 100 + e[\"column\"] }", 203},
+        // rethrowing keeps the message
+        {`try { try { throw "inner" } catch (e) { throw e } } catch (e) { e["message"] }`, "inner"},
+        {"let f = fn() { try { return 1 } catch (e) { 2 }; 3 }; f()", 1},
+        {"let f = fn() { try { 1 + true } catch (e) { return 2 }; 3 }; f()", 2},
+        // errors from deep inside calls are caught too
+        {"let inner = fn() { 1 / 0 }; let outer = fn() { inner() + 1 }; try { outer() } catch (e) { 7 }", 7},
+        // a catch that fails replaces the original error
+        {`try { 1 / 0 } catch (e) { throw "again" }`, errorMessage("again")},
+        // uncaught errors still propagate
+        {`throw "up"; 5`, errorMessage("up")},
+    }
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+        testExpectedObject(t, tt.input, evaluated, tt.expected)
+    }
+}
+
+
+func TestTryFinally(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected interface{}
+    }{
+        {"let f = fn() { let x = [0]; try { x } finally { 9 } }; f()", []int64{0}},
+        {"try { 1 } catch (e) { 2 } finally { 3 }", 1},
+        {"try { 1 / 0 } catch (e) { 2 } finally { 3 }", 2},
+        {"try { 1 / 0 } finally { 3 }", errorMessage("division by zero")},
+        {"let f = fn() { try { return 1 } finally { return 2 } }; f()", 2},
+        {`try { 1 } finally { throw "from finally" }`, errorMessage("from finally")},
+    }
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+        testExpectedObject(t, tt.input, evaluated, tt.expected)
+    }
+}
+
+
+func TestCaughtErrorTrace(t *testing.T) {
+    input := `let inner = fn() { throw "deep" };
+let outer = fn() { inner() };
+try { outer() } catch (e) { e["trace"] }`
+
+    evaluated := testEval(input)
+    arr, ok := evaluated.(*object.Array)
+    if !ok {
+        t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+    }
+    expected := []string{"inner (1:20) [1 tail call elided]", "<main> (3:7)"}
+    if len(arr.Elements) != len(expected) {
+        t.Fatalf("wrong trace length. expected=%d, got=%d (%s)", len(expected), len(arr.Elements), arr.Inspect())
+    }
+    for i, line := range expected {
+        if arr.Elements[i].Inspect() != line {
+            t.Errorf("trace[%d] wrong. expected=%q, got=%q", i, line, arr.Elements[i].Inspect())
+        }
+    }
+}
+
+
+func TestRethrowKeepsOrigin(t *testing.T) {
+    input := `let inner = fn() { 1 + true };
+let outer = fn() { 1 + inner() };
+let handler = fn() {
+    try { 1 + outer() } catch (e) { throw e }
+};
+try { 1 + handler() } catch (e) { e }`
+
+    evaluated := testEval(input)
+    hash, ok := evaluated.(*object.Hash)
+    if !ok {
+        t.Fatalf("object is not Hash. got=%T (%+v)", evaluated, evaluated)
+    }
+    expected := `{message: type mismatch: INTEGER + BOOLEAN, line: 1, column: 20, ` +
+        `trace: [inner (1:20), outer (2:24), handler (4:15), <main> (6:11)]}`
+    if hash.Inspect() != expected {
+        t.Errorf("wrong error after rethrowing.\nexpected=%s\ngot=     %s", expected, hash.Inspect())
+    }
+
+    tests := []struct {
+        input    string
+        expected interface{}
+    }{
+        // uncaught, the rethrown error is still reported where it happened
+        {"let f = fn() { 1 / 0 };\ntry { 1 + f() } catch (e) { throw e }", errorMessage("division by zero")},
+        {"let f = fn() { 1 / 0 };\nlet e = try { 1 + f() } catch (e) { e }; let g = fn() { throw e }; try { g() } catch (e) { e[\"trace\"][0] + \", \" + e[\"trace\"][1] }", "f (1:16), <main> (2:19)"},
+        {"let e = try { 1 / 0 } catch (e) { e }; let g = fn() { 1 + error(e) }; try { g() } catch (e) { e[\"line\"] * 100 + e[\"column\"] }", 115},
+        // a changed message goes with the original position
+        {"try { try {\n  1 / 0 } catch (e) { e[\"message\"] = \"wrapped\"; throw e } } catch (e) { \"${e[\"message\"]} ${e[\"line\"]}\" }", "wrapped 2"},
+        // a hash that was not caught is an error where it is thrown
+        {"try {\n  throw {\"message\": \"new\"} } catch (e) { e[\"line\"] }", 2},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+        testExpectedObject(t, tt.input, evaluated, tt.expected)
+    }
+}
+
+
+func TestTailCalls(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected interface{}
+    }{
+        {"let count = fn(n) { if (n == 0) { 0 } else { count(n - 1) } }; count(1000000)", 0},
+        {"let count = fn(n) { if (n == 0) { return 0 }; return count(n - 1) }; count(1000000)", 0},
+        {"let sum = fn(n, acc) { if (n == 0) { acc } else { sum(n - 1, acc + n) } }; sum(100000, 0)", 5000050000},
+        {`let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };
+let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };
+even(100001)`, false},
+        {"let f = fn(n) { if (n == 0) { len([1, 2]) } else { f(n - 1) } }; f(10)", 2},
+        {"let f = fn(n) { try { if (n == 0) { throw \"done\" }; return f(n - 1) } catch (e) { e[\"message\"] } }; f(3)", "done"},
+        {"let g = fn(n) { if (n == 0) { 0 } else { 1 + g(n - 1) } }; g(1000)", 1000},
+        {"let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(100000)", errorMessage("maximum recursion depth exceeded")},
+        {"let f = fn(a, b) { a }; let g = fn() { f(1) }; g()", errorMessage("wrong number of arguments. got=1, want=2")},
+    }
+
+    for _, tt := range tests {
+        testExpectedObject(t, tt.input, testEval(tt.input), tt.expected)
+    }
+}
+
+// A tail call takes over the frame of the call that made it, but not where that was called from
+func TestTailCallTrace(t *testing.T) {
+    input := `let inner = fn() { 1 + true };
+let middle = fn() { inner() };
+let outer = fn() { 1 + middle() };
+outer();`
+
+    evaluated := testEval(input)
+    errObj, ok := evaluated.(*object.Error)
+    if !ok {
+        t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+    }
+    expected := []string{"inner (1:20) [1 tail call elided]", "outer (3:24)", "<main> (4:1)"}
+    lines := errObj.TraceLines()
+    if len(lines) != len(expected) {
+        t.Fatalf("wrong trace length. expected=%d, got=%d (%v)", len(expected), len(lines), lines)
+    }
+    for i, line := range expected {
+        if lines[i] != line {
+            t.Errorf("trace[%d] wrong. expected=%q, got=%q", i, line, lines[i])
+        }
+    }
+}
+
+
+func TestWhileLoops(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected interface{}
+    }{
+        {"while (false) { 1 }", nil},
+        {"while (true) { break }", nil},
+        {"let f = fn() { while (true) { return 5 } }; f()", 5},
+        {"let f = fn(x) { while (x) { if (x) { return 1 }; break } }; f(false)", nil},
+        {"while (1 + true) { 1 }", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+        {"while (true) { 1 + true }", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+        {"while (true) { try { break } finally { 1 } }", nil},
+    }
+
+    for _, tt := range tests {
+        testExpectedObject(t, tt.input, testEval(tt.input), tt.expected)
+    }
+}
+
+
+func TestForLoops(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected interface{}
+    }{
+        {"for (x in [1, 2]) { x }", nil},
+        {"let f = fn(xs) { for (x in xs) { if (x > 1) { return x } } }; f([1, 2, 3])", 2},
+        {"let f = fn(xs) { for (x in xs) { if (x < 3) { continue }; return x } }; f([1, 2, 3, 4])", 3},
+        {"let f = fn(xs) { for (i, x in xs) { if (x == 30) { return i } } }; f([10, 20, 30])", 2},
+        {"let f = fn(xs) { for (x in xs) { break; return x } }; f([1])", nil},
+        {`let f = fn() { for (k in {"a": 1}) { return k } }; f()`, "a"},
+        {`let f = fn() { for (k, v in {"a": 1}) { return [k, v] } }; f()[1]`, 1},
+        {`let f = fn() { for (c in "abc") { return c } }; f()`, "a"},
+        {`let f = fn() { for (i, c in "abc") { if (i == 2) { return c } } }; f()`, "c"},
+        {"let f = fn() { for (i in range(10, 0, -3)) { if (i < 5) { return i } } }; f()", 4},
+        {"let f = fn() { for (i, n in range(5, 10)) { if (n == 7) { return i } } }; f()", 2},
+        {"let f = fn() { for (i in range(3)) { return i } }; f()", 0},
+        {"let f = fn() { for (i in range(0)) { return i } }; f()", nil},
+        {"for (i in range(1000000)) { continue }", nil},
+        {"for (x in 5) { x }", errorMessage("cannot iterate over INTEGER")},
+        {"range(1, 2, 0)", errorMessage("range step must not be zero")},
+        {"range(true)", errorMessage("arguments to `range` must be 64-bit INTEGER, got=BOOLEAN")},
+    }
+
+    for _, tt := range tests {
+        testExpectedObject(t, tt.input, testEval(tt.input), tt.expected)
+    }
+}
+
+
+func TestAssignment(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected interface{}
+    }{
+        {"let x = 1; x = 2; x", 2},
+        {"let x = 1; x = 2", 2},
+        {"let x = 1; let y = 1; x = y = 5; x + y", 10},
+        {"let x = 10; x += 5; x -= 3; x *= 2; x /= 4; x", 6},
+        {"let s = \"a\"; s += \"b\"; s", "ab"},
+        {"x = 1", errorMessage("cannot assign to undeclared identifier: x")},
+        {"x += 1", errorMessage("identifier not found: x")},
+        {"let x = 1; x += true", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+        {"let x = 1; x /= 0", errorMessage("division by zero")},
+        {"let x = 1; let f = fn() { x = 2 }; f(); x", 2},
+        {"let x = 1; let f = fn() { let x = 5; x = 2 }; f(); x", 1},
+        {"let counter = fn() { let n = 0; fn() { n += 1 } }; let c = counter(); c(); c(); c()", 3},
+        {"let x = 0; for (i in range(5)) { x += i }; x", 10},
+        {"let i = 0; while (i < 10) { i += 1; if (i == 3) { break } }; i", 3},
+        {"let n = 0; let i = 0; while (i < 5) { i += 1; if (i == 2) { continue }; n += i }; n", 13},
+    }
+
+    for _, tt := range tests {
+        testExpectedObject(t, tt.input, testEval(tt.input), tt.expected)
+    }
+}
+
+
+func TestIndexAssignment(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected interface{}
+    }{
+        {"let a = [1, 2, 3]; a[0] = 10; a", []int64{10, 2, 3}},
+        {"let a = [1, 2, 3]; a[2] += 5; a", []int64{1, 2, 8}},
+        {"let a = [1, 2, 3]; let b = a; b[1] = 0; a", []int64{1, 0, 3}},
+        {"let a = [[1], [2]]; a[1][0] *= 7; a[1]", []int64{14}},
+        {`let a = [1]; a[0] = a; "${a}"`, "[[...]]"},
+        {`let h = {}; h["h"] = h; "${h}"`, "{h: {...}}"},
+        {"let a = [1]; a[1] = 2", errorMessage("index out of range: 1")},
+        {"let a = [1, 2]; a[-1] = 5; a", []int64{1, 5}},
+        {"let a = [1]; a[-2] = 2", errorMessage("index out of range: -2")},
+        {"let a = [1]; a[\"x\"] = 2", errorMessage("array index must be INTEGER, got=STRING")},
+        {"let h = {}; h[\"a\"] = 1; h[\"a\"]", 1},
+        {"let h = {\"a\": 1}; h[\"a\"] += 1; h[\"a\"]", 2},
+        {"let h = {}; h[fn() { 1 }] = 1", errorMessage("unusable as hash key: FUNCTION")},
+        {"let h = {}; h[\"a\"] += 1", errorMessage("type mismatch: NULL + INTEGER")},
+        {"let s = \"abc\"; s[0] = \"x\"", errorMessage("index assignment not supported: STRING")},
+    }
+
+    for _, tt := range tests {
+        testExpectedObject(t, tt.input, testEval(tt.input), tt.expected)
+    }
+}
+
+
+// Evaluated without the parser's checks, which would catch most of these first
+func TestConst(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected interface{}
+    }{
+        {"const x = 1; x", 1},
+        {"const x = 1; x = 2", errorMessage("cannot assign to constant x")},
+        {"const x = 1; x += 2", errorMessage("cannot assign to constant x")},
+        {"const x = 1; let x = 2", errorMessage("cannot redeclare constant x")},
+        {"const x = 1; const x = 2", errorMessage("cannot redeclare constant x")},
+        {"let f = fn() { x = 2 }; const x = 1; f()", errorMessage("cannot assign to constant x")},
+        {"const x = 1; let f = fn() { let x = 2; x = 3; x }; f()", 3},
+        {"const x = 1; let f = fn(x) { x = 5; x }; f(2)", 5},
+        {"let x = 1; const x = 2; x", 2},
+        {"const a = [1, 2]; a[0] = 5; a", []int64{5, 2}},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := parser.New(l)
+        program := p.ParseProgram()
+        env := object.NewEnvironment()
+        testExpectedObject(t, tt.input, Eval(program, env), tt.expected)
+    }
+}
+
+
+func TestConstAcrossInputs(t *testing.T) {
+    env := object.NewEnvironment()
+    for _, input := range []string{"const limit = 10", "let f = fn() { limit }"} {
+        Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+    }
+
+    // a later input, like the next line in the REPL, is parsed without knowing about the constant
+    evaluated := Eval(parser.New(lexer.New("let limit = 20")).ParseProgram(), env)
+    testExpectedObject(t, "let limit = 20", evaluated, errorMessage("cannot redeclare constant limit"))
+    evaluated = Eval(parser.New(lexer.New("f()")).ParseProgram(), env)
+    testExpectedObject(t, "f()", evaluated, 10)
+}
+
+
+func TestLogicalOperators(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected interface{}
+    }{
+        {"true && true", true},
+        {"true && false", false},
+        {"false || true", true},
+        {"false || false", false},
+        {"1 < 2 && 2 < 3", true},
+        {"1 && 2", 2},
+        {"if (false) { 1 } && 2", nil},
+        {"0 || 5", 0},
+        {"let h = {}; h[\"missing\"] || \"default\"", "default"},
+        {"false && (1 + true)", false},
+        {"true || (1 + true)", true},
+        {"true && (1 + true)", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+        {"(1 + true) || true", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+        {"let n = 0; false && (n = 1); true || (n = 2); n", 0},
+        {"let n = 0; true && (n = 1); n", 1},
+        {"let f = fn(n) { n == 0 || f(n - 1) }; f(100000)", true},
+        {"let f = fn(n) { n > 0 && f(n - 1) }; f(100000)", false},
+    }
+
+    for _, tt := range tests {
+        testExpectedObject(t, tt.input, testEval(tt.input), tt.expected)
+    }
+}
+
+
+func TestArithmeticOperators(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected interface{}
+    }{
+        {"2 ** 10", 1024},
+        {"2 ** 3 ** 2", 512},
+        {"-2 ** 2", -4},
+        {"(-2) ** 3", -8},
+        {"7 ** 0", 1},
+        {"0 ** 0", 1},
+        {"7 % 3", 1},
+        {"-7 % 3", -1},
+        {"6 & 3", 2},
+        {"6 | 3", 7},
+        {"6 ^ 3", 5},
+        {"~5", -6},
+        {"1 << 10", 1024},
+        {"-16 >> 2", -4},
+        {"1 >> 100", 0},
+        {"0 << 100", 0},
+        {"1 + 2 << 1", 6},
+        {"1 << -1", errorMessage("negative shift count: -1")},
+        {"1 >> -1", errorMessage("negative shift count: -1")},
+        {"~true", errorMessage("unknown operator: ~BOOLEAN")},
+        {"1.5 & 1", errorMessage("unknown operator: FLOAT & INTEGER")},
+    }
+
+    for _, tt := range tests {
+        testExpectedObject(t, tt.input, testEval(tt.input), tt.expected)
+    }
+
+    floats := []struct {
+        input    string
+        expected float64
+    }{
+        {"2 ** -1", 0.5},
+        {"2.0 ** 3", 8},
+        {"4 ** 0.5", 2},
+    }
+    for _, tt := range floats {
+        testFloatObject(t, testEval(tt.input), tt.expected)
+    }
+
+    bigs := []struct {
+        input    string
+        expected string
+    }{
+        {"2 ** 64", "18446744073709551616"},
+        {"3 ** 41", "36472996377170786403"},
+        {"1 << 64", "18446744073709551616"},
+        {"-1 << 63", "-9223372036854775808"},
+        {"(1 << 64) >> 64", "1"},
+        {"(1 << 64) | 1", "18446744073709551617"},
+        {"~(1 << 64)", "-18446744073709551617"},
+        {"(2 ** 64) ** 2", "340282366920938463463374607431768211456"},
+    }
+    for _, tt := range bigs {
+        evaluated := testEval(tt.input)
+        if evaluated.Inspect() != tt.expected {
+            t.Errorf("wrong result for %q. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+        }
+    }
+}
+
+
+func TestComparisonOperators(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected bool
+    }{
+        {"1 <= 2", true},
+        {"2 <= 2", true},
+        {"3 <= 2", false},
+        {"1 >= 2", false},
+        {"2 >= 2", true},
+        {"1.5 <= 1", false},
+        {"1.5 >= 1", true},
+        {"(1 << 64) >= (1 << 64)", true},
+        {"(1 << 64) <= 1", false},
+        {`"a" < "b"`, true},
+        {`"b" < "a"`, false},
+        {`"abc" > "abd"`, false},
+        {`"ab" < "abc"`, true},
+        {`"a" <= "a"`, true},
+        {`"b" >= "a"`, true},
+        {`"foo" == "foo"`, true},
+        {`"foo" == "bar"`, false},
+        {`"foo" != "bar"`, true},
+        {`"fo" + "o" == "foo"`, true},
+    }
+
+    for _, tt := range tests {
+        testBooleanObject(t, testEval(tt.input), tt.expected)
+    }
+}
+
+
+func TestCheckedPowerAndShift(t *testing.T) {
+    CheckedArithmetic = true
+    defer func() { CheckedArithmetic = false }()
+
+    testExpectedObject(t, "2 ** 64", testEval("2 ** 64"), errorMessage("integer overflow: 2 ** 64"))
+    testExpectedObject(t, "1 << 64", testEval("1 << 64"), errorMessage("integer overflow: 1 << 64"))
+    testExpectedObject(t, "2 ** 62", testEval("2 ** 62"), 4611686018427387904)
+}
+
+
+func TestIntegerSizeLimit(t *testing.T) {
+    power := errorMessage("result too large: ** would make an integer of more than 1048576 bits")
+    shift := errorMessage("result too large: << would make an integer of more than 1048576 bits")
+
+    tests := []struct {
+        input    string
+        expected interface{}
+    }{
+        {"2 ** 100000000000", power},
+        {"(-3) ** 9223372036854775807", power},
+        {"(2 ** 1000) ** 2000", power},
+        {"1 << 9223372036854775807", shift},
+        {"1 << 100000000000", shift},
+        {"(2 ** 1048575) << 1", shift},
+        {"try { 2 ** 100000000000 } catch (e) { 1 }", 1},
+        {"1 ** 9223372036854775807", 1},
+        {"(-1) ** 9223372036854775807", -1},
+        {"0 ** 9223372036854775807", 0},
+        {"0 << 9223372036854775807", 0},
+        {"1 >> 9223372036854775807", 0},
+        {"(2 ** 1048575) >> 1048574", 2},
+        {"(1 << 1048575) >> 1048575", 1},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+        testExpectedObject(t, tt.input, evaluated, tt.expected)
+    }
+}
+
+
+func TestStructuralEquality(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected bool
+    }{
+        {"[1, 2] == [1, 2]", true},
+        {"1 == 1.0", true},
+        {"9007199254740992 == 9007199254740992.0", true},
+        {"[9007199254740992] == [9007199254740992.0]", true},
+        {"9007199254740993 == 9007199254740992.0", false},
+        {"9007199254740992.0 == 9007199254740993", false},
+        {"9007199254740993 != 9007199254740992.0", true},
+        {"[9007199254740993] == [9007199254740992.0]", false},
+        {"2 ** 64 == 18446744073709551616.0", true},
+        // ordering agrees with ==
+        {"9007199254740993 <= 9007199254740992.0", false},
+        {"9007199254740993 > 9007199254740992.0", true},
+        {"9007199254740993 >= 9007199254740992.0", true},
+        {"9007199254740992.0 < 9007199254740993", true},
+        {"9007199254740992 <= 9007199254740992.0", true},
+        {"2.0 ** 2000 > 2 ** 2000", true},
+        {"-(2.0 ** 2000) < -(2 ** 2000)", true},
+        // NaN is not equal to anything, itself included
+        {"let n = (-1.0) ** 0.5; n == n", false},
+        {"let n = (-1.0) ** 0.5; n != n", true},
+        {"let n = (-1.0) ** 0.5; [n] == [n]", false},
+        {"let n = (-1.0) ** 0.5; n == 1 || n < 1 || n > 1 || n <= 1 || n >= 1", false},
+        {"let n = (-1.0) ** 0.5; n != 1", true},
+        {"[1, 2] != [1, 2]", false},
+        {"[1, 2] == [2, 1]", false},
+        {"[1, [2, 3]] == [1, [2, 3]]", true},
+        {"[1] == [1.0]", true},
+        {`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+        {`{"a": 1} == {"a": 2}`, false},
+        {`{"a": 1} == {"a": 1, "b": 2}`, false},
+        {`[1, "a", true] == [1, "a", true]`, true},
+        {"[] == {}", false},
+        {"[1] == 1", false},
+        {"if (false) { 1 } == if (false) { 2 }", true},
+        {"let f = fn() { 1 }; f == f", true},
+        {"fn() { 1 } == fn() { 1 }", false},
+        {"let a = [1, 0]; a[1] = a; let b = [1, 0]; b[1] = b; a == b", true},
+        {"let a = [1, 0]; a[1] = a; let b = [2, 0]; b[1] = b; a == b", false},
+        {"range(3) == range(0, 3)", true},
+    }
+
+    for _, tt := range tests {
+        testBooleanObject(t, testEval(tt.input), tt.expected)
+    }
+}
+
+
+func TestCompositeHashKeys(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected interface{}
+    }{
+        {"let h = {[1, 2]: 5}; h[[1, 2]]", 5},
+        {"let h = {[1, 2]: 5}; h[[2, 1]]", nil},
+        {"let h = {[1, [2, \"a\"]]: 5}; h[[1, [2, \"a\"]]]", 5},
+        {"let h = {}; h[[0, 0]] = 1; h[[0, 1]] = 2; h[[0, 0]] + h[[0, 1]]", 3},
+        {"let h = {}; h[[0, 0]] = 1; h[[0, 0]] += 1; h[[0, 0]]", 2},
+        {"let k = [1]; let h = {}; h[k] = 5; k[0] = 2; h[[1]]", 5},
+        {"let k = [1]; let h = {}; h[k] = 5; k[0] = 2; h[k]", nil},
+        {"let a = [1, 0]; a[1] = a; {}[a]", errorMessage("unusable as hash key: ARRAY")},
+        {"{[fn() { 1 }]: 1}", errorMessage("unusable as hash key: ARRAY")},
+        {"{{\"a\": 1}: 1}", errorMessage("unusable as hash key: HASH")},
+        {"let h = {freeze({\"a\": 1, \"b\": 2}): 5}; h[freeze({\"b\": 2, \"a\": 1})]", 5},
+        {"let k = freeze({\"a\": [1]}); {k: 5}[k]", 5},
+        {"let k = freeze({\"a\": 1}); k[\"a\"] = 2", errorMessage("cannot assign to a frozen hash")},
+        {"let k = freeze({\"a\": 1}); k[\"a\"]", 1},
+        {"freeze([1])", errorMessage("argument to `freeze` must be HASH, got=ARRAY")},
+    }
+
+    for _, tt := range tests {
+        testExpectedObject(t, tt.input, testEval(tt.input), tt.expected)
+    }
+}
+
+
+func TestHashOrder(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected string
+    }{
+        {`{"b": 1, "a": 2, "c": 3}`, `{b: 1, a: 2, c: 3}`},
+        {`{3: 1, 1: 2, 2: 3}`, `{3: 1, 1: 2, 2: 3}`},
+        {`let h = {"b": 1, "a": 2}; h["c"] = 3; h["b"] = 4; h`, `{b: 4, a: 2, c: 3}`},
+        {`let keys = []; for (k in {"z": 1, "y": 2, "x": 3}) { keys = push(keys, k) }; keys`, `[z, y, x]`},
+        {`let vs = []; for (k, v in {"z": 1, "y": 2, "x": 3}) { vs = push(vs, v) }; vs`, `[1, 2, 3]`},
+        {`{[2, 1]: "a", [1, 2]: "b"}`, `{[2, 1]: a, [1, 2]: b}`},
+    }
+
+    for _, tt := range tests {
+        // more than once, a map would come out in a different order sooner or later
+        for i := 0; i < 10; i++ {
+            evaluated := testEval(tt.input)
+            if evaluated.Inspect() != tt.expected {
+                t.Errorf("wrong result for %q. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+                break
+            }
+        }
+    }
+}
+
+
+type errorMessage string
+
+// Checks obj against expected, which can be an int, int64 slice (an array), string, bool, nil
+// (for NULL) or errorMessage
+func testExpectedObject(t *testing.T, input string, obj object.Object, expected interface{}) bool {
+    switch expected := expected.(type) {
+    case int:
+        return testIntegerObject(t, obj, int64(expected))
+    case []int64:
+        arr, ok := obj.(*object.Array)
+        if !ok {
+            t.Errorf("%q: object is not Array. got=%T (%+v)", input, obj, obj)
+            return false
+        }
+        if len(arr.Elements) != len(expected) {
+            t.Errorf("%q: array has wrong length. got=%d, want=%d", input, len(arr.Elements), len(expected))
+            return false
+        }
+        for i, el := range expected {
+            if !testIntegerObject(t, arr.Elements[i], el) {
+                return false
+            }
+        }
+        return true
+    case string:
+        str, ok := obj.(*object.String)
+        if !ok {
+            t.Errorf("%q: object is not String. got=%T (%+v)", input, obj, obj)
+            return false
+        }
+        if str.Value != expected {
+            t.Errorf("%q: String has wrong value. got=%q, want=%q", input, str.Value, expected)
+            return false
+        }
+        return true
+    case bool:
+        return testBooleanObject(t, obj, expected)
+    case nil:
+        return testNullObject(t, obj)
+    case errorMessage:
+        errObj, ok := obj.(*object.Error)
+        if !ok {
+            t.Errorf("%q: object is not Error. got=%T (%+v)", input, obj, obj)
+            return false
+        }
+        if errObj.Message != string(expected) {
+            t.Errorf("%q: wrong error message. expected=%q, got=%q", input, expected, errObj.Message)
+            return false
+        }
+        return true
+    }
+    t.Errorf("%q: type of expected not handled. got=%T", input, expected)
+    return false
+}
+
+
 func TestLetStatements(t *testing.T) {
     tests := []struct {
         input      string
@@ -342,6 +1291,27 @@ func TestStringConcatenation(t *testing.T) {
 }
 
 
+func TestStringInterpolation(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected interface{}
+    }{
+        {`let name = "Ann"; let items = [1, 2]; "hello ${name}, you have ${len(items)} items"`, "hello Ann, you have 2 items"},
+        {`"${1 + 2}${"x"}"`, "3x"},
+        {`let a = "b"; "a${"-${a}-"}c"`, "a-b-c"},
+        {`"${[1, 2]} ${{"k": true}} ${if (false) { 1 }}"`, "[1, 2] {k: true} null"},
+        {`"\${x}"`, "${x}"},
+        {`let f = fn(x) { "<${x}>" }; f(f("y"))`, "<<y>>"},
+        {`"a ${x} b"`, errorMessage("identifier not found: x")},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+        testExpectedObject(t, tt.input, evaluated, tt.expected)
+    }
+}
+
+
 func TestBuiltinFunctions(t *testing.T) {
     tests := []struct {
         input string
@@ -350,6 +1320,12 @@ func TestBuiltinFunctions(t *testing.T) {
         {`len("")`, 0},
         {`len("four")`, 4},
         {`len("hello world")`, 11},
+        {`len("héllo")`, 5},
+        {`len("xin chào")`, 8},
+        {`len("😀")`, 1},
+        {`bytelen("héllo")`, 6},
+        {`bytelen("😀")`, 4},
+        {`bytelen(1)`, "argument to `bytelen` must be STRING, got=INTEGER"},
         {`len(1)`, "argument to `len` not supported, got INTEGER"},
         {`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
     }
@@ -372,6 +1348,26 @@ func TestBuiltinFunctions(t *testing.T) {
 }
 
 
+func TestUnicodeStrings(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected interface{}
+    }{
+        {`let größe = 3; größe * 2`, 6},
+        {`let tên = "Việt"; tên`, "Việt"},
+        {`let s = ""; for (c in "chào") { s += c + "," }; s`, "c,h,à,o,"},
+        {`let n = 0; for (i, c in "ñé😀") { n = i }; n`, 2},
+        {`bytes("é")`, []int64{195, 169}},
+        {`bytes("")`, []int64{}},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+        testExpectedObject(t, tt.input, evaluated, tt.expected)
+    }
+}
+
+
 func TestArrayLiterals(t *testing.T) {
     input := "[1, 2 * 2, 3 + 3]"
     evaluated := testEval(input)
@@ -431,6 +1427,18 @@ func TestArrayIndexExpressions(t *testing.T) {
         },
         {
             "[1, 2, 3][-1]",
+            3,
+        },
+        {
+            "[1, 2, 3][-3]",
+            1,
+        },
+        {
+            "[1, 2, 3][-4]",
+            nil,
+        },
+        {
+            "[1, 2, 3][2 ** 70]",
             nil,
         },
     }
@@ -446,6 +1454,95 @@ func TestArrayIndexExpressions(t *testing.T) {
 }
 
 
+func TestStringIndexExpressions(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected interface{}
+    }{
+        {`"abc"[0]`, "a"},
+        {`"abc"[2]`, "c"},
+        {`"abc"[-1]`, "c"},
+        {`"héllo"[1]`, "é"},
+        {`"chào"[-2]`, "à"},
+        {`let s = "abc"; let out = ""; for (i in range(len(s))) { out = s[i] + out }; out`, "cba"},
+        {`"abc"[3]`, nil},
+        {`"abc"[-4]`, nil},
+        {`""[0]`, nil},
+        {`"abc"["a"]`, errorMessage("index operator not supported: STRING")},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+        testExpectedObject(t, tt.input, evaluated, tt.expected)
+    }
+}
+
+
+func TestSliceExpressions(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected interface{}
+    }{
+        {"[1, 2, 3, 4, 5][1:3]", []int64{2, 3}},
+        {"[1, 2, 3, 4, 5][:2]", []int64{1, 2}},
+        {"[1, 2, 3, 4, 5][3:]", []int64{4, 5}},
+        {"[1, 2, 3, 4, 5][:]", []int64{1, 2, 3, 4, 5}},
+        {"[1, 2, 3, 4, 5][-2:]", []int64{4, 5}},
+        {"[1, 2, 3, 4, 5][:-2]", []int64{1, 2, 3}},
+        {"[1, 2, 3, 4, 5][::2]", []int64{1, 3, 5}},
+        {"[1, 2, 3, 4, 5][1::2]", []int64{2, 4}},
+        {"[1, 2, 3, 4, 5][::-1]", []int64{5, 4, 3, 2, 1}},
+        {"[1, 2, 3, 4, 5][3:0:-1]", []int64{4, 3, 2}},
+        {"[1, 2, 3, 4, 5][-1:-4:-2]", []int64{5, 3}},
+        {"[1, 2, 3, 4, 5][2:100]", []int64{3, 4, 5}},
+        {"[1, 2, 3, 4, 5][-100:2]", []int64{1, 2}},
+        {"[1, 2, 3, 4, 5][100:-100:-1]", []int64{5, 4, 3, 2, 1}},
+        {"[1, 2, 3, 4, 5][3:1]", []int64{}},
+        {"[1, 2, 3, 4, 5][::9223372036854775807]", []int64{1}},
+        {"[1, 2, 3, 4, 5][::-(2 ** 70)]", []int64{5}},
+        {"[][:]", []int64{}},
+        {"let a = [1, 2, 3]; let b = a[:]; b[0] = 9; a", []int64{1, 2, 3}},
+        {`"hello"[1:4]`, "ell"},
+        {`"hello"[::-1]`, "olleh"},
+        {`"xin chào"[4:]`, "chào"},
+        {`"héllo"[:2]`, "hé"},
+        {`"abc"[5:]`, ""},
+        {"[1, 2][::0]", errorMessage("slice step must not be zero")},
+        {`[1, 2]["a":]`, errorMessage("slice index must be INTEGER, got=STRING")},
+        {"5[1:2]", errorMessage("slice operator not supported: INTEGER")},
+        {"[1, 2][x:]", errorMessage("identifier not found: x")},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+        testExpectedObject(t, tt.input, evaluated, tt.expected)
+    }
+}
+
+
+func TestStrictIndexing(t *testing.T) {
+    StrictIndexing = true
+    defer func() { StrictIndexing = false }()
+
+    tests := []struct {
+        input    string
+        expected interface{}
+    }{
+        {"[1, 2, 3][3]", errorMessage("index out of range: 3")},
+        {"[1, 2, 3][-4]", errorMessage("index out of range: -4")},
+        {`"abc"[5]`, errorMessage("index out of range: 5")},
+        {"[1, 2, 3][-1]", 3},
+        {"[1, 2, 3][1:100]", []int64{2, 3}},
+        {`{"a": 1}["b"]`, nil},
+    }
+
+    for _, tt := range tests {
+        evaluated := testEval(tt.input)
+        testExpectedObject(t, tt.input, evaluated, tt.expected)
+    }
+}
+
+
 func TestHashLiterals(t *testing.T) {
     input := ` let two = "two";
     {
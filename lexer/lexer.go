@@ -1,22 +1,64 @@
 package lexer
 
-// TODO: Support Unicode for indentifier
-
 import(
     "A-Plus-Plus/token"
+    "fmt"
+    "strings"
+    "unicode"
+    "unicode/utf8"
 )
 
+// Called with problems the lexer finds in the source, like an unterminated string. The lexer
+// still returns a token for them, so a handler is only needed to report them
+type ErrorHandler func(pos token.Position, msg string)
+
 type Lexer struct {
     input string
     position int // position of the current char
     read_position int // position where we are currently reading after the current char (since we need to peek further into the input)
-    ch byte // current char
+    ch rune // current char, decoded from UTF-8. Positions are byte offsets, so it can be more than one byte long
+
+    filename string
+    line int // line of the current char
+    column int // column of the current char
+
+    errorHandler ErrorHandler // nil to ignore errors
+    keepComments bool
+
+    // The ${...} in strings we are inside of, innermost last
+    interpolations []interpolation
+}
+
+type interpolation struct {
+    depth int // how many { are open inside it, so we know which } ends it
+    start token.Position // the opening quote of its string
+}
+
+func (l *Lexer) SetErrorHandler(h ErrorHandler) {
+    l.errorHandler = h
+}
+
+// Attach the comments before each token to it, instead of throwing them away
+func (l *Lexer) SetKeepComments(keep bool) {
+    l.keepComments = keep
+}
+
+func (l *Lexer) error(pos token.Position, msg string) {
+    if l.errorHandler != nil {
+        l.errorHandler(pos, msg)
+    }
 }
 
 func (l *Lexer) NextToken() token.Token {
-    var tok token.Token
+    comments := l.skipWhitespace()
+    tok := l.readToken()
+    tok.Comments = comments
+    return tok
+}
 
-    l.skipWhitespace()
+func (l *Lexer) readToken() token.Token {
+    var tok token.Token
+    pos := l.currentPosition()
 
     switch l.ch {
     case '=':
@@ -32,32 +74,94 @@ func (l *Lexer) NextToken() token.Token {
     case ')':
         tok = newToken(token.RPAREN, l.ch)
     case '{':
+        if n := len(l.interpolations); n > 0 {
+            l.interpolations[n-1].depth += 1
+        }
         tok = newToken(token.LBRACE, l.ch)
     case '}':
-        tok = newToken(token.RBRACE, l.ch)
+        n := len(l.interpolations)
+        if n > 0 && l.interpolations[n-1].depth == 0 {
+            tok = l.readStringToken(pos)
+        } else {
+            if n > 0 {
+                l.interpolations[n-1].depth -= 1
+            }
+            tok = newToken(token.RBRACE, l.ch)
+        }
     case ',':
         tok = newToken(token.COMMA, l.ch)
     case '+':
-        tok = newToken(token.PLUS, l.ch)
+        if l.peekChar() == '=' {
+            tok = l.makeTwoCharTok()
+        } else {
+            tok = newToken(token.PLUS, l.ch)
+        }
     case '-':
-        tok = newToken(token.MINUS, l.ch)
+        if l.peekChar() == '=' {
+            tok = l.makeTwoCharTok()
+        } else {
+            tok = newToken(token.MINUS, l.ch)
+        }
     case '<':
-        tok = newToken(token.LT, l.ch)
+        if l.peekChar() == '=' || l.peekChar() == '<' {
+            tok = l.makeTwoCharTok()
+        } else {
+            tok = newToken(token.LT, l.ch)
+        }
     case '>':
-        tok = newToken(token.GT, l.ch)
+        if l.peekChar() == '=' || l.peekChar() == '>' {
+            tok = l.makeTwoCharTok()
+        } else {
+            tok = newToken(token.GT, l.ch)
+        }
     case '*':
-        tok = newToken(token.ASTERISK, l.ch)
+        if l.peekChar() == '=' || l.peekChar() == '*' {
+            tok = l.makeTwoCharTok()
+        } else {
+            tok = newToken(token.ASTERISK, l.ch)
+        }
     case '/':
-        tok = newToken(token.SLASH, l.ch)
+        if l.peekChar() == '*' {
+            // skipWhitespace stops before a comment that is not closed
+            tok = l.unterminatedComment(pos)
+        } else if l.peekChar() == '=' {
+            tok = l.makeTwoCharTok()
+        } else {
+            tok = newToken(token.SLASH, l.ch)
+        }
+    case '%':
+        tok = newToken(token.PERCENT, l.ch)
     case '!':
         if l.peekChar() == '=' {
             tok = l.makeTwoCharTok()
         } else {
             tok = newToken(token.BANG, l.ch)
         }
+    case '&':
+        if l.peekChar() == '&' {
+            tok = l.makeTwoCharTok()
+        } else {
+            tok = newToken(token.BIT_AND, l.ch)
+        }
+    case '|':
+        if l.peekChar() == '|' {
+            tok = l.makeTwoCharTok()
+        } else {
+            tok = newToken(token.BIT_OR, l.ch)
+        }
+    case '^':
+        tok = newToken(token.BIT_XOR, l.ch)
+    case '~':
+        tok = newToken(token.BIT_NOT, l.ch)
     case '"':
+        tok = l.readStringToken(pos)
+    case '`':
+        var ok bool
         tok.Type = token.STRING
-        tok.Literal = l.readString()
+        tok.Literal, ok = l.readRawString()
+        if !ok {
+            tok = l.unterminatedString(pos)
+        }
     case '[':
         tok = newToken(token.LBRACKET, l.ch)
     case ']':
@@ -71,10 +175,13 @@ func (l *Lexer) NextToken() token.Token {
         if isLetter(l.ch) {
             tok.Literal = l.readIdentifier()
             tok.Type = token.LookupIdent(tok.Literal)
+            tok.Pos = pos
+            tok.End = l.currentPosition()
             return tok
         } else if isDigit(l.ch) {
-            tok.Type = token.INT
-            tok.Literal = l.readNumber()
+            tok.Type, tok.Literal = l.readNumber()
+            tok.Pos = pos
+            tok.End = l.currentPosition()
             return tok
         } else {
             tok = newToken(token.ILLEGAL, l.ch)
@@ -82,9 +189,22 @@ func (l *Lexer) NextToken() token.Token {
     }
 
     l.readChar()
+    if !tok.Pos.IsValid() {
+        tok.Pos = pos
+    }
+    tok.End = l.currentPosition()
     return tok
 }
 
+func (l *Lexer) currentPosition() token.Position {
+    return token.Position{
+        Filename: l.filename,
+        Offset: l.position,
+        Line: l.line,
+        Column: l.column,
+    }
+}
+
 func (l *Lexer) makeTwoCharTok() token.Token {
     ch := l.ch
     l.readChar()
@@ -93,76 +213,356 @@ func (l *Lexer) makeTwoCharTok() token.Token {
     return tok
 }
 
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
     if l.read_position >= len(l.input) {
         return 0
     } else {
-        return l.input[l.read_position]
+        ch, _ := utf8.DecodeRuneInString(l.input[l.read_position:])
+        return ch
     }
 }
 
+// Like peekChar, but looks n bytes past the start of the current char
+func (l *Lexer) peekCharN(n int) rune {
+    if l.position + n >= len(l.input) {
+        return 0
+    }
+    ch, _ := utf8.DecodeRuneInString(l.input[l.position + n:])
+    return ch
+}
+
+// Marks like the accents in "ế" can follow a letter when the text is not normalized, so they are
+// part of the identifier too
 func (l *Lexer) readIdentifier() string {
     position := l.position
-    for isLetter(l.ch) {
+    for isLetter(l.ch) || unicode.Is(unicode.Mn, l.ch) {
         l.readChar()
     }
     return l.input[position:l.position]
 }
 
 
-func newToken(TokenType token.TokenType, ch byte) token.Token {
+func newToken(TokenType token.TokenType, ch rune) token.Token {
     return token.Token{Type: TokenType, Literal: string(ch)}
 }
 
-func (l *Lexer) skipWhitespace() {
-    for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+// Skips whitespace and comments. Returns the comments if we keep them
+func (l *Lexer) skipWhitespace() []token.Comment {
+    var comments []token.Comment
+    for {
+        pos := l.currentPosition()
+        switch {
+        case l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r':
+            l.readChar()
+            continue
+        case l.ch == '/' && l.peekChar() == '/':
+            for l.ch != '\n' && l.ch != 0 {
+                l.readChar()
+            }
+        case l.ch == '/' && l.peekChar() == '*':
+            end := blockCommentEnd(l.input, l.position)
+            if end < 0 {
+                return comments
+            }
+            for l.position < end {
+                l.readChar()
+            }
+        default:
+            return comments
+        }
+        if l.keepComments {
+            comments = append(comments, token.Comment{Text: l.input[pos.Offset:l.position], Pos: pos})
+        }
+    }
+}
+
+// Finds the end of the /* */ comment starting at offset. They nest, so every /* inside it needs
+// its own */. Returns the offset just past the last */, or -1 if the comment is not closed
+func blockCommentEnd(input string, offset int) int {
+    depth := 0
+    for i := offset; i + 1 < len(input); i++ {
+        switch input[i:i+2] {
+        case "/*":
+            depth += 1
+            i += 1
+        case "*/":
+            depth -= 1
+            i += 1
+            if depth == 0 {
+                return i + 1
+            }
+        }
+    }
+    return -1
+}
+
+// Everything from the /* to the end of the input, like an unterminated string
+func (l *Lexer) unterminatedComment(start token.Position) token.Token {
+    l.error(start, "comment not terminated")
+    for l.ch != 0 {
         l.readChar()
     }
+    return token.Token{Type: token.ILLEGAL, Literal: l.input[start.Offset:]}
 }
 
 func (l *Lexer) readChar() {
     // Change l.ch to next char and update position and read_position
+    if l.ch == '\n' {
+        l.line += 1
+        l.column = 1
+    } else {
+        l.column += 1
+    }
+    size := 0
     if l.read_position >= len(l.input) {
         l.ch = 0 // NUL
     } else {
-        l.ch = l.input[l.read_position]
+        // invalid UTF-8 comes out as one utf8.RuneError per byte
+        l.ch, size = utf8.DecodeRuneInString(l.input[l.read_position:])
     }
     l.position = l.read_position // where we last read
-    l.read_position += 1 // where we are going to read
+    l.read_position += size // where we are going to read
 }
 
 func New(input string) *Lexer {
-    l := &Lexer{input: input}
+    return NewWithFilename("", input)
+}
+
+// Same as New, but every token position also records which file it came from
+func NewWithFilename(filename string, input string) *Lexer {
+    l := &Lexer{input: input, filename: filename, line: 1}
     l.readChar()
     return l
 }
 
-func (l *Lexer) readNumber() string {
-    // TODO: read floats and hex, or even octal
+func (l *Lexer) readNumber() (token.TokenType, string) {
     position := l.position
-    for isDigit(l.ch) {
+
+    // 0x, 0o and 0b literals. Every letter and digit after the prefix belongs to the literal, so the
+    // parser can point at a bad digit instead of us quietly splitting "0x1g" into two tokens
+    if l.ch == '0' && isBasePrefix(l.peekChar()) {
+        l.readChar()
         l.readChar()
+        for isLetter(l.ch) || isDigit(l.ch) {
+            l.readChar()
+        }
+        return token.INT, l.input[position:l.position]
     }
-    return l.input[position:l.position]
+
+    tokType := token.TokenType(token.INT)
+    l.readDigits()
+
+    // "1." or "1.foo" are not floats, there has to be a digit after the dot
+    if l.ch == '.' && isDigit(l.peekChar()) {
+        tokType = token.FLOAT
+        l.readChar()
+        l.readDigits()
+    }
+
+    if (l.ch == 'e' || l.ch == 'E') && l.isExponentStart() {
+        tokType = token.FLOAT
+        l.readChar()
+        if l.ch == '+' || l.ch == '-' {
+            l.readChar()
+        }
+        l.readDigits()
+    }
+
+    return tokType, l.input[position:l.position]
 }
 
+// Digits may be separated by underscores, like 1_000_000. The parser checks they are used properly
+func (l *Lexer) readDigits() {
+    for isDigit(l.ch) || l.ch == '_' {
+        l.readChar()
+    }
+}
 
-func isDigit(ch byte) bool {
+// l.ch is an 'e'. It only starts an exponent if digits follow, with an optional sign in between
+func (l *Lexer) isExponentStart() bool {
+    next := l.peekChar()
+    if next == '+' || next == '-' {
+        next = l.peekCharN(2)
+    }
+    return isDigit(next)
+}
+
+
+func isDigit(ch rune) bool {
     return '0' <= ch && ch <= '9'
 }
 
-func isLetter(ch byte) bool {
-    return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+func isBasePrefix(ch rune) bool {
+    switch ch {
+    case 'x', 'X', 'o', 'O', 'b', 'B':
+        return true
+    default:
+        return false
+    }
+}
+
+func isLetter(ch rune) bool {
+    if ch < utf8.RuneSelf {
+        return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+    }
+    return unicode.IsLetter(ch)
+}
+
+
+// Makes the token for a "..." string, starting on its opening quote, or for the rest of one after
+// an interpolation, starting on the } that ends it
+func (l *Lexer) readStringToken(pos token.Position) token.Token {
+    continued := l.ch == '}'
+    start := pos
+    if continued {
+        n := len(l.interpolations)
+        start = l.interpolations[n-1].start
+        l.interpolations = l.interpolations[:n-1]
+    }
+
+    value, end := l.readString()
+    switch {
+    case end == stringUnterminated:
+        return l.unterminatedString(start)
+    case end == stringInterpolation:
+        l.interpolations = append(l.interpolations, interpolation{start: start})
+        if continued {
+            return token.Token{Type: token.INTERP_MID, Literal: value}
+        }
+        return token.Token{Type: token.INTERP_START, Literal: value}
+    case continued:
+        return token.Token{Type: token.INTERP_END, Literal: value}
+    default:
+        return token.Token{Type: token.STRING, Literal: value}
+    }
+}
+
+// Everything from the opening quote to the end of the input, so it is clear what went wrong. That
+// includes the start of the string when this is the part after a ${...}
+func (l *Lexer) unterminatedString(start token.Position) token.Token {
+    l.error(start, "string literal not terminated")
+    return token.Token{Type: token.ILLEGAL, Literal: l.input[start.Offset:], Pos: start}
 }
 
+// How readString stopped
+const (
+    stringClosed = iota // on the closing quote
+    stringInterpolation // on the { of a ${
+    stringUnterminated // at the end of the input
+)
+
+// Reads the text of a "..." string from just after the current char. Returns it with the escapes
+// decoded, and how it ended
+func (l *Lexer) readString() (string, int) {
+    var out strings.Builder
+    for {
+        l.readChar()
+        switch l.ch {
+        case '"':
+            return out.String(), stringClosed
+        case 0:
+            return out.String(), stringUnterminated
+        case '$':
+            if l.peekChar() == '{' {
+                l.readChar()
+                return out.String(), stringInterpolation
+            }
+            out.WriteRune(l.ch)
+        case '\\':
+            l.readEscape(&out)
+        default:
+            // the bytes as they are, so invalid UTF-8 is kept instead of turning into U+FFFD
+            out.WriteString(l.input[l.position:l.read_position])
+        }
+    }
+}
+
+// Reads the escape starting at the current backslash, and leaves l.ch on its last char. Bad ones
+// are reported and kept as they were written
+func (l *Lexer) readEscape(out *strings.Builder) {
+    pos := l.currentPosition()
+    l.readChar()
+
+    switch l.ch {
+    case 'n':
+        out.WriteByte('\n')
+    case 't':
+        out.WriteByte('\t')
+    case 'r':
+        out.WriteByte('\r')
+    case '\\', '"', '$':
+        out.WriteRune(l.ch)
+    case 'u':
+        l.readUnicodeEscape(out, pos)
+    case 0:
+        // the string is not terminated, readString reports that
+    default:
+        l.error(pos, fmt.Sprintf("unknown escape sequence \\%c", l.ch))
+        out.WriteByte('\\')
+        out.WriteString(l.input[l.position:l.read_position])
+    }
+}
+
+// \u{1F600}, with 1 to 6 hex digits. pos is where the backslash is
+func (l *Lexer) readUnicodeEscape(out *strings.Builder, pos token.Position) {
+    start := l.position - 1
+    if l.peekChar() != '{' {
+        l.error(pos, "invalid unicode escape, expected \\u{...}")
+        out.WriteString(l.input[start:l.read_position])
+        return
+    }
+    l.readChar()
 
-func (l *Lexer) readString() string {
+    digits := 0
+    value := rune(0)
+    for isHexDigit(l.peekChar()) {
+        l.readChar()
+        digits += 1
+        if digits <= 6 {
+            value = value * 16 + rune(hexValue(l.ch))
+        }
+    }
+    if l.peekChar() != '}' {
+        l.error(pos, "invalid unicode escape, expected \\u{...}")
+        out.WriteString(l.input[start:l.read_position])
+        return
+    }
+    l.readChar()
+
+    if digits == 0 || digits > 6 || !utf8.ValidRune(value) {
+        l.error(pos, fmt.Sprintf("invalid unicode code point %s", l.input[start+2:l.read_position]))
+        out.WriteString(l.input[start:l.read_position])
+        return
+    }
+    out.WriteRune(value)
+}
+
+// Reads a `...` string, which has no escapes and can span lines. Starts on the opening backtick
+// and stops on the closing one
+func (l *Lexer) readRawString() (string, bool) {
     position := l.position + 1
     for {
         l.readChar()
-        if l.ch == '"' || l.ch == 0 {
-            break
+        if l.ch == '`' {
+            return l.input[position:l.position], true
+        }
+        if l.ch == 0 {
+            return l.input[position:l.position], false
         }
     }
-    return l.input[position:l.position]
+}
+
+func isHexDigit(ch rune) bool {
+    return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func hexValue(ch rune) int {
+    switch {
+    case isDigit(ch):
+        return int(ch - '0')
+    case 'a' <= ch && ch <= 'f':
+        return int(ch - 'a') + 10
+    default:
+        return int(ch - 'A') + 10
+    }
 }
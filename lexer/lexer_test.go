@@ -13,7 +13,7 @@ func TestNextToken(t *testing.T) {
     };
 
     let result = add(five, ten);
-    !-/*5;
+    !-/ *5;
     5 < 10 > 5;
 
     if (5 < 10) {
@@ -139,3 +139,513 @@ func TestNextToken(t *testing.T) {
 
 
 
+func TestTokenPositions(t *testing.T) {
+    input := "let x = 5;\n  x + 10;\n\"hi\""
+
+    tests := []struct {
+        expected_literal string
+        expected_line int
+        expected_column int
+        expected_offset int
+    }{
+        {"let", 1, 1, 0},
+        {"x", 1, 5, 4},
+        {"=", 1, 7, 6},
+        {"5", 1, 9, 8},
+        {";", 1, 10, 9},
+        {"x", 2, 3, 13},
+        {"+", 2, 5, 15},
+        {"10", 2, 7, 17},
+        {";", 2, 9, 19},
+        {"hi", 3, 1, 21},
+        {"", 3, 5, 25},
+    }
+
+    l := NewWithFilename("test.apl", input)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+        if tok.Literal != tt.expected_literal {
+            t.Fatalf("tests[%d]: wrong Literal. Expected: %q, got: %q", i, tt.expected_literal, tok.Literal)
+        }
+        if tok.Pos.Line != tt.expected_line || tok.Pos.Column != tt.expected_column {
+            t.Errorf("tests[%d]: wrong position for %q. Expected: %d:%d, got: %d:%d", i, tok.Literal,
+                tt.expected_line, tt.expected_column, tok.Pos.Line, tok.Pos.Column)
+        }
+        if tok.Pos.Offset != tt.expected_offset {
+            t.Errorf("tests[%d]: wrong offset for %q. Expected: %d, got: %d", i, tok.Literal, tt.expected_offset, tok.Pos.Offset)
+        }
+        if tok.Pos.Filename != "test.apl" {
+            t.Errorf("tests[%d]: wrong filename. Expected: %q, got: %q", i, "test.apl", tok.Pos.Filename)
+        }
+    }
+}
+
+func TestNumbers(t *testing.T) {
+    input := `5 3.14 1.5e-3 2E10 7e+2 1. 1.x 2e x1e5`
+
+    tests := []struct {
+        expected_type token.TokenType
+        expected_literal string
+    }{
+        {token.INT, "5"},
+        {token.FLOAT, "3.14"},
+        {token.FLOAT, "1.5e-3"},
+        {token.FLOAT, "2E10"},
+        {token.FLOAT, "7e+2"},
+        {token.INT, "1"},
+        {token.ILLEGAL, "."},
+        {token.INT, "1"},
+        {token.ILLEGAL, "."},
+        {token.IDENT, "x"},
+        {token.INT, "2"},
+        {token.IDENT, "e"},
+        {token.IDENT, "x"},
+        {token.FLOAT, "1e5"},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+        if tok.Type != tt.expected_type {
+            t.Fatalf("tests[%d]: wrong TokenType. Expected: %q, got: %q", i, tt.expected_type, tok.Type)
+        }
+
+        if tok.Literal != tt.expected_literal {
+            t.Fatalf("tests[%d]: wrong Literal. Expected: %q, got: %q", i, tt.expected_literal, tok.Literal)
+        }
+    }
+}
+
+func TestIntegerBases(t *testing.T) {
+    input := `0xFF 0o17 0b1010 1_000_000 0x1g 0b 1_000.5 1__0`
+
+    tests := []struct {
+        expected_type token.TokenType
+        expected_literal string
+    }{
+        {token.INT, "0xFF"},
+        {token.INT, "0o17"},
+        {token.INT, "0b1010"},
+        {token.INT, "1_000_000"},
+        {token.INT, "0x1g"},
+        {token.INT, "0b"},
+        {token.FLOAT, "1_000.5"},
+        {token.INT, "1__0"},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+        if tok.Type != tt.expected_type {
+            t.Fatalf("tests[%d]: wrong TokenType. Expected: %q, got: %q", i, tt.expected_type, tok.Type)
+        }
+
+        if tok.Literal != tt.expected_literal {
+            t.Fatalf("tests[%d]: wrong Literal. Expected: %q, got: %q", i, tt.expected_literal, tok.Literal)
+        }
+    }
+}
+
+
+func TestTwoCharOperators(t *testing.T) {
+    input := `x = 1; x += 2; x -= 3; x *= 4; x /= 5; x == += && || <= >= << >> ** & | ^ ~ < > * *=`
+
+    tests := []struct {
+        expected_type token.TokenType
+        expected_literal string
+    }{
+        {token.IDENT, "x"},
+        {token.ASSIGN, "="},
+        {token.INT, "1"},
+        {token.SEMICOLON, ";"},
+        {token.IDENT, "x"},
+        {token.PLUS_ASSIGN, "+="},
+        {token.INT, "2"},
+        {token.SEMICOLON, ";"},
+        {token.IDENT, "x"},
+        {token.MINUS_ASSIGN, "-="},
+        {token.INT, "3"},
+        {token.SEMICOLON, ";"},
+        {token.IDENT, "x"},
+        {token.ASTERISK_ASSIGN, "*="},
+        {token.INT, "4"},
+        {token.SEMICOLON, ";"},
+        {token.IDENT, "x"},
+        {token.SLASH_ASSIGN, "/="},
+        {token.INT, "5"},
+        {token.SEMICOLON, ";"},
+        {token.IDENT, "x"},
+        {token.EQ, "=="},
+        {token.PLUS_ASSIGN, "+="},
+        {token.AND, "&&"},
+        {token.OR, "||"},
+        {token.LT_EQ, "<="},
+        {token.GT_EQ, ">="},
+        {token.SHIFT_LEFT, "<<"},
+        {token.SHIFT_RIGHT, ">>"},
+        {token.POWER, "**"},
+        {token.BIT_AND, "&"},
+        {token.BIT_OR, "|"},
+        {token.BIT_XOR, "^"},
+        {token.BIT_NOT, "~"},
+        {token.LT, "<"},
+        {token.GT, ">"},
+        {token.ASTERISK, "*"},
+        {token.ASTERISK_ASSIGN, "*="},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+        if tok.Type != tt.expected_type {
+            t.Fatalf("tests[%d]: wrong TokenType. Expected: %q, got: %q", i, tt.expected_type, tok.Type)
+        }
+        if tok.Literal != tt.expected_literal {
+            t.Fatalf("tests[%d]: wrong Literal. Expected: %q, got: %q", i, tt.expected_literal, tok.Literal)
+        }
+    }
+}
+
+func TestStrings(t *testing.T) {
+    input := "\"a\\nb\" \"tab\\there\" \"q\\\"uote\" \"back\\\\slash\" \"\\u{48}\\u{e9}\\u{1F600}\" `raw\\n\"string\"` `two\nlines` \"multi\nline\" \"\""
+
+    tests := []string{
+        "a\nb",
+        "tab\there",
+        "q\"uote",
+        "back\\slash",
+        "Hé😀",
+        "raw\\n\"string\"",
+        "two\nlines",
+        "multi\nline",
+        "",
+    }
+
+    errors := []string{}
+    l := New(input)
+    l.SetErrorHandler(func(pos token.Position, msg string) {
+        errors = append(errors, msg)
+    })
+
+    for i, expected := range tests {
+        tok := l.NextToken()
+        if tok.Type != token.STRING {
+            t.Fatalf("tests[%d]: wrong TokenType. Expected: %q, got: %q", i, token.STRING, tok.Type)
+        }
+        if tok.Literal != expected {
+            t.Errorf("tests[%d]: wrong Literal. Expected: %q, got: %q", i, expected, tok.Literal)
+        }
+    }
+    if tok := l.NextToken(); tok.Type != token.EOF {
+        t.Errorf("expected EOF, got %q", tok.Type)
+    }
+    if len(errors) != 0 {
+        t.Errorf("unexpected errors: %q", errors)
+    }
+}
+
+func TestStringErrors(t *testing.T) {
+    tests := []struct {
+        input            string
+        expected_type    token.TokenType
+        expected_literal string
+        expected_errors  []string
+    }{
+        {`"abc`, token.ILLEGAL, `"abc`, []string{"1:1: string literal not terminated"}},
+        {"x `abc\ndef", token.ILLEGAL, "`abc\ndef", []string{"1:3: string literal not terminated"}},
+        {`"abc\"`, token.ILLEGAL, `"abc\"`, []string{"1:1: string literal not terminated"}},
+        {`"a\qb"`, token.STRING, `a\qb`, []string{`1:3: unknown escape sequence \q`}},
+        {`"\u41"`, token.STRING, `\u41`, []string{`1:2: invalid unicode escape, expected \u{...}`}},
+        {`"\u{41"`, token.STRING, `\u{41`, []string{`1:2: invalid unicode escape, expected \u{...}`}},
+        {`"\u{}"`, token.STRING, `\u{}`, []string{"1:2: invalid unicode code point {}"}},
+        {`"\u{110000}"`, token.STRING, `\u{110000}`, []string{"1:2: invalid unicode code point {110000}"}},
+        {`"\u{D800}"`, token.STRING, `\u{D800}`, []string{"1:2: invalid unicode code point {D800}"}},
+    }
+
+    for _, tt := range tests {
+        errors := []string{}
+        l := New(tt.input)
+        l.SetErrorHandler(func(pos token.Position, msg string) {
+            errors = append(errors, pos.String() + ": " + msg)
+        })
+
+        tok := l.NextToken()
+        if tok.Type == token.IDENT {
+            tok = l.NextToken()
+        }
+        if tok.Type != tt.expected_type || tok.Literal != tt.expected_literal {
+            t.Errorf("wrong token for %q. Expected: %s %q, got: %s %q", tt.input, tt.expected_type,
+                tt.expected_literal, tok.Type, tok.Literal)
+        }
+        if l.NextToken().Type != token.EOF {
+            t.Errorf("input %q not used up", tt.input)
+        }
+        if len(errors) != len(tt.expected_errors) || (len(errors) > 0 && errors[0] != tt.expected_errors[0]) {
+            t.Errorf("wrong errors for %q. Expected: %q, got: %q", tt.input, tt.expected_errors, errors)
+        }
+    }
+}
+
+func TestTokenEnd(t *testing.T) {
+    input := "let abc = \"a\\nb\";\n`x\ny`"
+
+    tests := []struct {
+        expected_line int
+        expected_column int
+        expected_offset int
+    }{
+        {1, 4, 3},
+        {1, 8, 7},
+        {1, 10, 9},
+        {1, 17, 16},
+        {1, 18, 17},
+        {3, 3, 23},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+        if tok.End.Line != tt.expected_line || tok.End.Column != tt.expected_column || tok.End.Offset != tt.expected_offset {
+            t.Errorf("tests[%d]: wrong end for %q. Expected: %d:%d (%d), got: %d:%d (%d)", i, tok.Literal,
+                tt.expected_line, tt.expected_column, tt.expected_offset, tok.End.Line, tok.End.Column, tok.End.Offset)
+        }
+    }
+}
+
+func TestInterpolation(t *testing.T) {
+    input := `"a ${x} b ${y + 1} c" "${f("${z}")}" "\${x}" "$x {y}"`
+
+    tests := []struct {
+        expected_type    token.TokenType
+        expected_literal string
+    }{
+        {token.INTERP_START, "a "},
+        {token.IDENT, "x"},
+        {token.INTERP_MID, " b "},
+        {token.IDENT, "y"},
+        {token.PLUS, "+"},
+        {token.INT, "1"},
+        {token.INTERP_END, " c"},
+        {token.INTERP_START, ""},
+        {token.IDENT, "f"},
+        {token.LPAREN, "("},
+        {token.INTERP_START, ""},
+        {token.IDENT, "z"},
+        {token.INTERP_END, ""},
+        {token.RPAREN, ")"},
+        {token.INTERP_END, ""},
+        {token.STRING, "${x}"},
+        {token.STRING, "$x {y}"},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+        if tok.Type != tt.expected_type {
+            t.Fatalf("tests[%d]: wrong TokenType. Expected: %q, got: %q", i, tt.expected_type, tok.Type)
+        }
+        if tok.Literal != tt.expected_literal {
+            t.Fatalf("tests[%d]: wrong Literal. Expected: %q, got: %q", i, tt.expected_literal, tok.Literal)
+        }
+    }
+}
+
+func TestInterpolationBraces(t *testing.T) {
+    // the } of the hash literal must not end the interpolation
+    l := New(`"${ {1: 2}[1] }!"`)
+
+    expected := []token.TokenType{
+        token.INTERP_START, token.LBRACE, token.INT, token.COLON, token.INT, token.RBRACE,
+        token.LBRACKET, token.INT, token.RBRACKET, token.INTERP_END, token.EOF,
+    }
+    for i, tt := range expected {
+        tok := l.NextToken()
+        if tok.Type != tt {
+            t.Fatalf("tests[%d]: wrong TokenType. Expected: %q, got: %q", i, tt, tok.Type)
+        }
+    }
+}
+
+func TestUnterminatedInterpolation(t *testing.T) {
+    errors := []string{}
+    l := New(`x = "a ${x} b`)
+    l.SetErrorHandler(func(pos token.Position, msg string) {
+        errors = append(errors, pos.String() + ": " + msg)
+    })
+
+    var tok token.Token
+    for tok = l.NextToken(); tok.Type != token.ILLEGAL && tok.Type != token.EOF; tok = l.NextToken() {
+    }
+    if tok.Type != token.ILLEGAL || tok.Literal != `"a ${x} b` || tok.Pos.Column != 5 {
+        t.Errorf("wrong token. Expected: ILLEGAL %q at 1:5, got: %s %q at %s", `"a ${x} b`, tok.Type, tok.Literal, tok.Pos)
+    }
+    if len(errors) != 1 || errors[0] != "1:5: string literal not terminated" {
+        t.Errorf("wrong errors: %q", errors)
+    }
+}
+
+func TestUnicode(t *testing.T) {
+    input := "let größe = \"héllo\";\nđiểm + ñ_1 · x\xff"
+
+    tests := []struct {
+        expected_type    token.TokenType
+        expected_literal string
+        expected_column  int
+    }{
+        {token.LET, "let", 1},
+        {token.IDENT, "größe", 5},
+        {token.ASSIGN, "=", 11},
+        {token.STRING, "héllo", 13},
+        {token.SEMICOLON, ";", 20},
+        {token.IDENT, "điểm", 1},
+        {token.PLUS, "+", 6},
+        {token.IDENT, "ñ_", 8},
+        {token.INT, "1", 10},
+        {token.ILLEGAL, "·", 12},
+        {token.IDENT, "x", 14},
+        {token.ILLEGAL, "\uFFFD", 15},
+        {token.EOF, "", 16},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+        if tok.Type != tt.expected_type || tok.Literal != tt.expected_literal {
+            t.Fatalf("tests[%d]: wrong token. Expected: %s %q, got: %s %q", i, tt.expected_type, tt.expected_literal, tok.Type, tok.Literal)
+        }
+        if tok.Pos.Column != tt.expected_column {
+            t.Errorf("tests[%d]: wrong column for %q. Expected: %d, got: %d", i, tok.Literal, tt.expected_column, tok.Pos.Column)
+        }
+    }
+}
+
+func TestCombiningMarks(t *testing.T) {
+    // "tiếng" with the accents as separate code points, the way some editors save it
+    input := "tie\u0302\u0301ng"
+
+    l := New(input)
+    tok := l.NextToken()
+    if tok.Type != token.IDENT || tok.Literal != input {
+        t.Errorf("wrong token. Expected: IDENT %q, got: %s %q", input, tok.Type, tok.Literal)
+    }
+}
+
+func TestComments(t *testing.T) {
+    input := `// a whole line
+let x = 10; // the rest of a line
+/* a block
+   over /* nested */ lines */ x /*inline*/ / 2 // at the end`
+
+    tests := []struct {
+        expected_type    token.TokenType
+        expected_literal string
+        expected_line    int
+    }{
+        {token.LET, "let", 2},
+        {token.IDENT, "x", 2},
+        {token.ASSIGN, "=", 2},
+        {token.INT, "10", 2},
+        {token.SEMICOLON, ";", 2},
+        {token.IDENT, "x", 4},
+        {token.SLASH, "/", 4},
+        {token.INT, "2", 4},
+        {token.EOF, "", 4},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+        if tok.Type != tt.expected_type || tok.Literal != tt.expected_literal {
+            t.Fatalf("tests[%d]: wrong token. Expected: %s %q, got: %s %q", i, tt.expected_type, tt.expected_literal, tok.Type, tok.Literal)
+        }
+        if tok.Pos.Line != tt.expected_line {
+            t.Errorf("tests[%d]: wrong line for %q. Expected: %d, got: %d", i, tok.Literal, tt.expected_line, tok.Pos.Line)
+        }
+        if tok.Comments != nil {
+            t.Errorf("tests[%d]: comments kept without SetKeepComments: %q", i, tok.Comments)
+        }
+    }
+}
+
+func TestKeepComments(t *testing.T) {
+    input := "// first\n// second\nx /* a /* b */ */ + y // last"
+
+    tests := []struct {
+        expected_literal string
+        expected_comments []token.Comment
+    }{
+        {"x", []token.Comment{
+            {Text: "// first", Pos: token.Position{Offset: 0, Line: 1, Column: 1}},
+            {Text: "// second", Pos: token.Position{Offset: 9, Line: 2, Column: 1}},
+        }},
+        {"+", []token.Comment{
+            {Text: "/* a /* b */ */", Pos: token.Position{Offset: 21, Line: 3, Column: 3}},
+        }},
+        {"y", nil},
+        {"", []token.Comment{
+            {Text: "// last", Pos: token.Position{Offset: 41, Line: 3, Column: 23}},
+        }},
+    }
+
+    l := New(input)
+    l.SetKeepComments(true)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+        if tok.Literal != tt.expected_literal {
+            t.Fatalf("tests[%d]: wrong Literal. Expected: %q, got: %q", i, tt.expected_literal, tok.Literal)
+        }
+        if len(tok.Comments) != len(tt.expected_comments) {
+            t.Errorf("tests[%d]: wrong comments. Expected: %v, got: %v", i, tt.expected_comments, tok.Comments)
+            continue
+        }
+        for j, c := range tt.expected_comments {
+            if tok.Comments[j] != c {
+                t.Errorf("tests[%d]: wrong comment %d. Expected: %v, got: %v", i, j, c, tok.Comments[j])
+            }
+        }
+    }
+}
+
+func TestUnterminatedComment(t *testing.T) {
+    tests := []struct {
+        input            string
+        expected_literal string
+        expected_error   string
+    }{
+        {"x /* abc", "/* abc", "1:3: comment not terminated"},
+        {"x /* a /* b */\nc", "/* a /* b */\nc", "1:3: comment not terminated"},
+    }
+
+    for _, tt := range tests {
+        errors := []string{}
+        l := New(tt.input)
+        l.SetErrorHandler(func(pos token.Position, msg string) {
+            errors = append(errors, pos.String() + ": " + msg)
+        })
+
+        l.NextToken()
+        tok := l.NextToken()
+        if tok.Type != token.ILLEGAL || tok.Literal != tt.expected_literal {
+            t.Errorf("wrong token for %q. Expected: ILLEGAL %q, got: %s %q", tt.input, tt.expected_literal, tok.Type, tok.Literal)
+        }
+        if l.NextToken().Type != token.EOF {
+            t.Errorf("input %q not used up", tt.input)
+        }
+        if len(errors) != 1 || errors[0] != tt.expected_error {
+            t.Errorf("wrong errors for %q. Expected: %q, got: %q", tt.input, tt.expected_error, errors)
+        }
+    }
+}
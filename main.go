@@ -1,14 +1,87 @@
 package main
 
 import(
+    "flag"
     "fmt"
+    "io"
     "os"
+    "A-Plus-Plus/evaluator"
+    "A-Plus-Plus/lexer"
+    "A-Plus-Plus/object"
+    "A-Plus-Plus/parser"
     "A-Plus-Plus/repl"
 )
 
+const USAGE = `usage:
+    %[1]s [flags]                          start the REPL
+    %[1]s [flags] run <file> [args...]     run a script, args are available to it as the array 'args'
+
+flags:
+`
+
 func main() {
-    fmt.Printf("Hello World!\n")
-    fmt.Printf("Please enter commands\n")
-    repl.Start(os.Stdin, os.Stdout)
+    checked := flag.Bool("checked", false, "report integer overflow as an error instead of switching to big integers")
+    strictIndex := flag.Bool("strict-index", false, "report indexing past the end of an array or string as an error instead of returning null")
+    flag.Usage = func() {
+        fmt.Fprintf(flag.CommandLine.Output(), USAGE, os.Args[0])
+        flag.PrintDefaults()
+    }
+    flag.Parse()
+    evaluator.CheckedArithmetic = *checked
+    evaluator.StrictIndexing = *strictIndex
+
+    args := flag.Args()
+    if len(args) == 0 {
+        fmt.Printf("Hello World!\n")
+        fmt.Printf("Please enter commands\n")
+        repl.Start(os.Stdin, os.Stdout)
+        return
+    }
+
+    switch args[0] {
+    case "run":
+        if len(args) < 2 {
+            flag.Usage()
+            os.Exit(2)
+        }
+        os.Exit(run(args[1], args[2:], os.Stderr))
+    default:
+        flag.Usage()
+        os.Exit(2)
+    }
 }
 
+// Runs the script at path and returns the exit status: 0 on success, 1 if it could not be read,
+// did not parse or evaluated to an error
+func run(path string, args []string, stderr io.Writer) int {
+    source, err := os.ReadFile(path)
+    if err != nil {
+        fmt.Fprintln(stderr, err)
+        return 1
+    }
+
+    l := lexer.NewWithFilename(path, string(source))
+    p := parser.New(l)
+    program := p.ParseProgram()
+    if len(p.Errors()) != 0 {
+        for _, msg := range p.Errors() {
+            fmt.Fprintln(stderr, msg)
+        }
+        return 1
+    }
+
+    env := object.NewEnvironment()
+    scriptArgs := make([]object.Object, len(args))
+    for i, arg := range args {
+        scriptArgs[i] = &object.String{Value: arg}
+    }
+    env.Set("args", &object.Array{Elements: scriptArgs})
+
+    evaluated := evaluator.Eval(program, env)
+    if errObj, ok := evaluated.(*object.Error); ok {
+        fmt.Fprintln(stderr, errObj.Inspect())
+        fmt.Fprint(stderr, errObj.StackTrace())
+        return 1
+    }
+    return 0
+}
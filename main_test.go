@@ -0,0 +1,62 @@
+package main
+
+import (
+    "bytes"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func writeScript(t *testing.T, source string) string {
+    path := filepath.Join(t.TempDir(), "script.apl")
+    if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+        t.Fatalf("could not write script: %s", err)
+    }
+    return path
+}
+
+func TestRun(t *testing.T) {
+    tests := []struct {
+        source         string
+        args           []string
+        expectedStatus int
+        expectedStderr string
+    }{
+        {"let x = 5; x * 2;", nil, 0, ""},
+        {`if (len(args) != 2) { 1 + true }; args[1];`, []string{"a", "b"}, 0, ""},
+        {`if (len(args) != 2) { 1 + true }; args[1];`, []string{"a"}, 1, ":1:23: type mismatch: INTEGER + BOOLEAN"},
+        {"let x = ;", nil, 1, ":1:9: no prefix parse function for ; found"},
+        {"let f = fn() { foobar };\nf();", nil, 1, ":1:16: identifier not found: foobar"},
+    }
+
+    for _, tt := range tests {
+        path := writeScript(t, tt.source)
+        var stderr bytes.Buffer
+        status := run(path, tt.args, &stderr)
+
+        if status != tt.expectedStatus {
+            t.Errorf("%q: wrong exit status. expected=%d, got=%d (stderr=%q)", tt.source, tt.expectedStatus, status, stderr.String())
+        }
+        if tt.expectedStderr == "" {
+            if stderr.Len() != 0 {
+                t.Errorf("%q: expected no output on stderr, got=%q", tt.source, stderr.String())
+            }
+            continue
+        }
+        if !strings.Contains(stderr.String(), path + tt.expectedStderr) {
+            t.Errorf("%q: stderr does not contain %q. got=%q", tt.source, path + tt.expectedStderr, stderr.String())
+        }
+    }
+}
+
+func TestRunMissingFile(t *testing.T) {
+    var stderr bytes.Buffer
+    status := run(filepath.Join(t.TempDir(), "nope.apl"), nil, &stderr)
+    if status != 1 {
+        t.Errorf("wrong exit status. expected=1, got=%d", status)
+    }
+    if stderr.Len() == 0 {
+        t.Errorf("expected an error on stderr")
+    }
+}
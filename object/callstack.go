@@ -0,0 +1,57 @@
+package object
+
+import (
+	"A-Plus-Plus/token"
+)
+
+// One function call that is in progress
+type Frame struct {
+    Function string // the name it was bound to with let, empty for anonymous functions
+    Pos token.Position // where it was called from
+    TailCalls int // how many tail calls took over this frame since, Function is the latest one
+}
+
+func (f Frame) Name() string {
+    if f.Function == "" {
+        return "<anonymous>"
+    }
+    return f.Function
+}
+
+// The calls the evaluator is currently inside of. Environments share one stack with the
+// environment they are enclosed in, so there is one per global environment
+type CallStack struct {
+    frames []Frame
+}
+
+func (s *CallStack) Push(f Frame) {
+    s.frames = append(s.frames, f)
+}
+
+func (s *CallStack) Pop() {
+    s.frames = s.frames[:len(s.frames)-1]
+}
+
+// For tail calls, where the new call takes the place of the one that made it. The frame keeps
+// where the first call was made from, since the caller is still waiting there
+func (s *CallStack) TailCall(function string) {
+    top := &s.frames[len(s.frames)-1]
+    top.Function = function
+    top.TailCalls += 1
+}
+
+func (s *CallStack) Depth() int {
+    return len(s.frames)
+}
+
+// A copy of the stack with the innermost call first, or nil if there are no calls
+func (s *CallStack) Trace() []Frame {
+    if len(s.frames) == 0 {
+        return nil
+    }
+    trace := make([]Frame, len(s.frames))
+    for i, f := range s.frames {
+        trace[len(s.frames)-1-i] = f
+    }
+    return trace
+}
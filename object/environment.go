@@ -4,12 +4,14 @@ package object
 
 func NewEnvironment() *Environment {
     s := make(map[string]Object)
-    return &Environment{store: s, outer: nil}
+    return &Environment{store: s, outer: nil, calls: &CallStack{}}
 }
 
 type Environment struct {
     store map[string]Object
+    consts map[string]bool // names in store that were declared with const, nil until there is one
     outer *Environment
+    calls *CallStack
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
@@ -26,9 +28,50 @@ func (e *Environment) Set(name string, val Object) Object {
 }
 
 
+// Binds name in this scope like Set, but won't replace a constant. Reports whether it did
+func (e *Environment) Define(name string, val Object, constant bool) bool {
+    if e.consts[name] {
+        return false
+    }
+    e.store[name] = val
+    if constant {
+        if e.consts == nil {
+            e.consts = make(map[string]bool)
+        }
+        e.consts[name] = true
+    }
+    return true
+}
+
+// Reports whether name refers to a constant, in the scope that defines it
+func (e *Environment) IsConst(name string) bool {
+    for env := e; env != nil; env = env.outer {
+        if _, ok := env.store[name]; ok {
+            return env.consts[name]
+        }
+    }
+    return false
+}
+
+// Changes an existing binding in the scope that defines it. Reports false if name is not defined
+// anywhere. Callers check IsConst first
+func (e *Environment) Assign(name string, val Object) bool {
+    for env := e; env != nil; env = env.outer {
+        if _, ok := env.store[name]; ok {
+            env.store[name] = val
+            return true
+        }
+    }
+    return false
+}
+
+
 func NewEnclosedEnvironment(outer *Environment) *Environment {
-    env := NewEnvironment()
-    env.outer = outer
-    return env
+    s := make(map[string]Object)
+    return &Environment{store: s, outer: outer, calls: outer.calls}
+}
+
+func (e *Environment) CallStack() *CallStack {
+    return e.calls
 }
 
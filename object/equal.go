@@ -0,0 +1,139 @@
+package object
+
+import (
+	"math"
+	"math/big"
+)
+
+// Reports whether a and b are the same value. Arrays and hashes are compared element by element,
+// numbers by value whether they are integers or floats, and anything else, like functions, only
+// equals itself. Use this for anything that has to find a value, so == and lookups agree
+func Equal(a, b Object) bool {
+    return equal(a, b, map[[2]Object]bool{})
+}
+
+// seen holds the pairs of arrays and hashes being compared further up. Running into one of them
+// again means we went around a cycle, and nothing along it has turned out different so far
+func equal(a, b Object, seen map[[2]Object]bool) bool {
+    // NaN is not equal to anything, even itself
+    if a == b && a.Type() != FLOAT_OBJ {
+        return true
+    }
+
+    switch a := a.(type) {
+    case *Integer:
+        return numbersEqual(a, b)
+    case *Float:
+        return numbersEqual(a, b)
+    case *String:
+        b, ok := b.(*String)
+        return ok && a.Value == b.Value
+    case *Boolean:
+        b, ok := b.(*Boolean)
+        return ok && a.Value == b.Value
+    case *Null:
+        _, ok := b.(*Null)
+        return ok
+    case *Range:
+        b, ok := b.(*Range)
+        return ok && *a == *b
+    case *Array:
+        b, ok := b.(*Array)
+        if !ok || len(a.Elements) != len(b.Elements) {
+            return false
+        }
+        pair := [2]Object{a, b}
+        if seen[pair] {
+            return true
+        }
+        seen[pair] = true
+        for i := range a.Elements {
+            if !equal(a.Elements[i], b.Elements[i], seen) {
+                return false
+            }
+        }
+        return true
+    case *Hash:
+        b, ok := b.(*Hash)
+        if !ok || len(a.Pairs) != len(b.Pairs) {
+            return false
+        }
+        pair := [2]Object{a, b}
+        if seen[pair] {
+            return true
+        }
+        seen[pair] = true
+        for _, aPair := range a.Pairs {
+            bPair, ok := b.Get(aPair.Key)
+            if !ok || !equal(aPair.Value, bPair.Value, seen) {
+                return false
+            }
+        }
+        return true
+    default:
+        return false
+    }
+}
+
+func numbersEqual(a, b Object) bool {
+    cmp, ok := CompareNumbers(a, b)
+    return ok && cmp == 0
+}
+
+// Compares two integers or floats exactly, unlike converting integers to float64, which rounds
+// big ones. Returns -1, 0 or 1 like big.Int.Cmp. ok is false if either is NaN, or not a number
+func CompareNumbers(a, b Object) (int, bool) {
+    if ai, ok := a.(*Integer); ok {
+        if bi, ok := b.(*Integer); ok {
+            if ai.Big == nil && bi.Big == nil {
+                switch {
+                case ai.Value < bi.Value:
+                    return -1, true
+                case ai.Value > bi.Value:
+                    return 1, true
+                }
+                return 0, true
+            }
+            return ai.BigValue().Cmp(bi.BigValue()), true
+        }
+    }
+
+    // two floats compare as they are, the way IEEE says
+    if af, ok := a.(*Float); ok {
+        if bf, ok := b.(*Float); ok {
+            switch {
+            case af.Value < bf.Value:
+                return -1, true
+            case af.Value > bf.Value:
+                return 1, true
+            case af.Value == bf.Value:
+                return 0, true
+            }
+            return 0, false
+        }
+    }
+
+    af, ok := exactFloat(a)
+    if !ok {
+        return 0, false
+    }
+    bf, ok := exactFloat(b)
+    if !ok {
+        return 0, false
+    }
+    return af.Cmp(bf), true
+}
+
+// A big.Float holding obj without rounding it. Infinities are fine, NaN is not
+func exactFloat(obj Object) (*big.Float, bool) {
+    switch obj := obj.(type) {
+    case *Integer:
+        return new(big.Float).SetInt(obj.BigValue()), true
+    case *Float:
+        if math.IsNaN(obj.Value) {
+            return nil, false
+        }
+        return big.NewFloat(obj.Value), true
+    }
+    return nil, false
+}
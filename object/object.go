@@ -2,9 +2,14 @@ package object
 
 import (
 	"A-Plus-Plus/ast"
+	"A-Plus-Plus/token"
 	"bytes"
+	"encoding/binary"
 	"fmt"
+	"hash"
 	"hash/fnv"
+	"math/big"
+	"strconv"
 	"strings"
 )
 
@@ -13,15 +18,20 @@ type BuiltinFunction func(args ...Object) Object
 
 const (
     INTEGER_OBJ     = "INTEGER"
+    FLOAT_OBJ       = "FLOAT"
     BOOLEAN_OBJ     = "BOOLEAN"
     NULL_OBJ        = "NULL"
     RETURN_VALUE_OBJ = "RETURN_VALUE"
+    TAIL_CALL_OBJ   = "TAIL_CALL"
+    BREAK_OBJ       = "BREAK"
+    CONTINUE_OBJ    = "CONTINUE"
     ERROR_OBJ       = "ERROR"
     FUNCTION_OBJ    = "FUNCTION"
     STRING_OBJ      = "STRING"
     BUILTIN_OBJ     = "BUILTIN"
     ARRAY_OBJ       = "ARRAY"
     HASH_OBJ        = "HASH"
+    RANGE_OBJ       = "RANGE"
 )
 
 type Object interface {
@@ -31,11 +41,50 @@ type Object interface {
 
 
 
+// Integers that don't fit in an int64 keep their value in Big instead, and Value is unused.
+// Create those with NewBigInteger so Big is only set when it has to be
 type Integer struct {
     Value int64
+    Big *big.Int
 }
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
-func (i *Integer) Inspect() string { return fmt.Sprintf("%d", i.Value) }
+func (i *Integer) Inspect() string {
+    if i.Big != nil {
+        return i.Big.String()
+    }
+    return fmt.Sprintf("%d", i.Value)
+}
+
+// The value as a big.Int, whatever its size. The result must not be modified
+func (i *Integer) BigValue() *big.Int {
+    if i.Big != nil {
+        return i.Big
+    }
+    return big.NewInt(i.Value)
+}
+
+// Wraps b in an Integer, using a plain int64 when it fits so that every value has one representation
+func NewBigInteger(b *big.Int) *Integer {
+    if b.IsInt64() {
+        return &Integer{Value: b.Int64()}
+    }
+    return &Integer{Big: b}
+}
+
+
+
+type Float struct {
+    Value float64
+}
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+func (f *Float) Inspect() string {
+    s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+    // keep whole floats looking like floats, 2.0 rather than 2
+    if !strings.ContainsAny(s, ".eIN") {
+        s += ".0"
+    }
+    return s
+}
 
 
 
@@ -61,15 +110,78 @@ func (rv *ReturnValue) Inspect() string { return rv.Value.Inspect() }
 
 
 
+// A call in tail position that the evaluator has not made yet. It travels back up to the
+// applyFunction that is running, which makes the call in a loop instead of recursing
+type TailCall struct {
+    Fn *Function
+    Args []Object
+    Pos token.Position // where the call happens
+}
+func (tc *TailCall) Type() ObjectType { return TAIL_CALL_OBJ }
+func (tc *TailCall) Inspect() string { return "tail call" }
+
+
+
+// break and continue, on their way out to the loop they are in
+type Break struct {}
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+func (b *Break) Inspect() string { return "break" }
+
+type Continue struct {}
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+func (c *Continue) Inspect() string { return "continue" }
+
+
+
 type Error struct {
     Message string
+    Pos token.Position // the innermost node that produced the error
+    Trace []Frame // the calls it happened in, innermost first
 }
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string { return "ERROR: " + e.Message }
+func (e *Error) Inspect() string {
+    if e.Pos.IsValid() {
+        return "ERROR: " + e.Pos.String() + ": " + e.Message
+    }
+    return "ERROR: " + e.Message
+}
+
+
+
+// One entry per function in the trace, each with the position execution had reached in it
+func (e *Error) TraceLines() []string {
+    if len(e.Trace) == 0 {
+        return nil
+    }
+    lines := []string{}
+    pos := e.Pos
+    for _, f := range e.Trace {
+        switch f.TailCalls {
+        case 0:
+            lines = append(lines, fmt.Sprintf("%s (%s)", f.Name(), pos))
+        case 1:
+            lines = append(lines, fmt.Sprintf("%s (%s) [1 tail call elided]", f.Name(), pos))
+        default:
+            lines = append(lines, fmt.Sprintf("%s (%s) [%d tail calls elided]", f.Name(), pos, f.TailCalls))
+        }
+        pos = f.Pos
+    }
+    lines = append(lines, fmt.Sprintf("<main> (%s)", pos))
+    return lines
+}
+
+func (e *Error) StackTrace() string {
+    var out bytes.Buffer
+    for _, line := range e.TraceLines() {
+        out.WriteString("    at " + line + "\n")
+    }
+    return out.String()
+}
 
 
 
 type Function struct {
+    Name        string // set when the function literal is bound with let
     Parameters  []*ast.Identifier
     Body        *ast.BlockStatement
     Env         *Environment
@@ -117,15 +229,50 @@ type Array struct {
 
 func (a *Array) Type() ObjectType { return ARRAY_OBJ }
 func (a *Array) Inspect() string {
-    var out bytes.Buffer
-    elements := []string {}
-    for _, e := range a.Elements {
-        elements = append(elements, e.Inspect())
+    return inspect(a, map[Object]bool{})
+}
+
+// Arrays and hashes can end up inside themselves through index assignment. The ones we are already
+// in the middle of printing further up come out as [...] or {...} instead of going around forever
+func inspect(obj Object, printing map[Object]bool) string {
+    switch obj := obj.(type) {
+    case *Array:
+        if printing[obj] {
+            return "[...]"
+        }
+        printing[obj] = true
+        defer delete(printing, obj)
+
+        var out bytes.Buffer
+        elements := []string {}
+        for _, e := range obj.Elements {
+            elements = append(elements, inspect(e, printing))
+        }
+        out.WriteString("[")
+        out.WriteString(strings.Join(elements, ", "))
+        out.WriteString("]")
+        return out.String()
+
+    case *Hash:
+        if printing[obj] {
+            return "{...}"
+        }
+        printing[obj] = true
+        defer delete(printing, obj)
+
+        var out bytes.Buffer
+        pairs := []string {}
+        for _, pair := range obj.OrderedPairs() {
+            pairs = append(pairs, fmt.Sprintf("%s: %s", inspect(pair.Key, printing), inspect(pair.Value, printing)))
+        }
+        out.WriteString("{")
+        out.WriteString(strings.Join(pairs, ", "))
+        out.WriteString("}")
+        return out.String()
+
+    default:
+        return obj.Inspect()
     }
-    out.WriteString("[")
-    out.WriteString(strings.Join(elements, ", "))
-    out.WriteString("]")
-    return out.String()
 }
 
 
@@ -146,6 +293,14 @@ func (b *Boolean) HashKey() HashKey {
 }
 
 func (i *Integer) HashKey() HashKey {
+    if i.Big != nil {
+        h := fnv.New64a()
+        if i.Big.Sign() < 0 {
+            h.Write([]byte{'-'})
+        }
+        h.Write(i.Big.Bytes())
+        return HashKey{Type: i.Type(), Value: h.Sum64()}
+    }
     return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
@@ -155,6 +310,34 @@ func (s *String) HashKey() HashKey {
     return HashKey{Type: s.Type(), Value: h.Sum64()}
 }
 
+// Only for arrays IsHashable accepts, it panics on elements that can't be hashed
+func (a *Array) HashKey() HashKey {
+    h := fnv.New64a()
+    for _, element := range a.Elements {
+        writeHashKey(h, element.(Hashable).HashKey())
+    }
+    return HashKey{Type: a.Type(), Value: h.Sum64()}
+}
+
+// Only for frozen hashes IsHashable accepts. The pairs are summed so their order doesn't matter
+func (h *Hash) HashKey() HashKey {
+    var sum uint64
+    for _, pair := range h.Pairs {
+        ph := fnv.New64a()
+        writeHashKey(ph, pair.Key.(Hashable).HashKey())
+        writeHashKey(ph, pair.Value.(Hashable).HashKey())
+        sum += ph.Sum64()
+    }
+    return HashKey{Type: h.Type(), Value: sum}
+}
+
+func writeHashKey(h hash.Hash64, key HashKey) {
+    var buf [8]byte
+    binary.LittleEndian.PutUint64(buf[:], key.Value)
+    h.Write([]byte(key.Type))
+    h.Write(buf[:])
+}
+
 
 
 type HashPair struct {
@@ -162,23 +345,118 @@ type HashPair struct {
     Value Object
 }
 
+// Different keys can have the same HashKey, so a pair is not always stored under its key's
+// HashKey. Use Get and Set, which look at the following HashKey values until they find the key
+// or a free one. Nothing is ever removed, which would leave a gap they stop at
 type Hash struct {
     Pairs map[HashKey]HashPair
+    Order []HashKey // where the pairs are in Pairs, in the order they were first set
+    Frozen bool // can't be changed, which makes it usable as a hash key
+    Caught *Error // set on the hash a catch block gets for an error, so throwing it again keeps its origin
+}
+
+func NewHash() *Hash {
+    return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+// The pairs in the order their keys were first set. Setting an existing key again doesn't move it
+func (h *Hash) OrderedPairs() []HashPair {
+    pairs := make([]HashPair, len(h.Order))
+    for i, hk := range h.Order {
+        pairs[i] = h.Pairs[hk]
+    }
+    return pairs
+}
+
+// key must be one IsHashable accepts
+func (h *Hash) Get(key Object) (HashPair, bool) {
+    hk := key.(Hashable).HashKey()
+    for {
+        pair, ok := h.Pairs[hk]
+        if !ok {
+            return HashPair{}, false
+        }
+        if Equal(pair.Key, key) {
+            return pair, true
+        }
+        hk.Value += 1
+    }
+}
+
+// key must be one IsHashable accepts. Arrays and hashes used as keys are copied, so changing the
+// original later can't change the key
+func (h *Hash) Set(key, value Object) {
+    hk := key.(Hashable).HashKey()
+    for {
+        pair, ok := h.Pairs[hk]
+        if !ok {
+            h.Pairs[hk] = HashPair{Key: copyKey(key), Value: value}
+            h.Order = append(h.Order, hk)
+            return
+        }
+        if Equal(pair.Key, key) {
+            h.Pairs[hk] = HashPair{Key: pair.Key, Value: value}
+            return
+        }
+        hk.Value += 1
+    }
+}
+
+func copyKey(key Object) Object {
+    switch key := key.(type) {
+    case *Array:
+        elements := make([]Object, len(key.Elements))
+        for i, element := range key.Elements {
+            elements[i] = copyKey(element)
+        }
+        return &Array{Elements: elements}
+    case *Hash:
+        hash := NewHash()
+        for _, hk := range key.Order {
+            pair := key.Pairs[hk]
+            hash.Pairs[hk] = HashPair{Key: pair.Key, Value: copyKey(pair.Value)}
+        }
+        hash.Order = append(hash.Order, key.Order...)
+        hash.Frozen = true
+        return hash
+    default:
+        return key
+    }
 }
 
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
 
 func (h *Hash) Inspect() string {
-    var out bytes.Buffer
+    return inspect(h, map[Object]bool{})
+}
 
-    pairs := []string {}
-    for _, pair := range h.Pairs {
-        pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
+
+
+// The integers from Start up to but not including End, Step apart. Made by the range builtin,
+// so a for loop can count without building an array first
+type Range struct {
+    Start int64
+    End int64
+    Step int64 // never 0
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+func (r *Range) Inspect() string {
+    if r.Step == 1 {
+        return fmt.Sprintf("range(%d, %d)", r.Start, r.End)
     }
-    out.WriteString("{")
-    out.WriteString(strings.Join(pairs, ", "))
-    out.WriteString("}")
-    return out.String()
+    return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.End, r.Step)
+}
+
+// How many integers there are. Worked out in uint64 because End - Start can overflow int64
+func (r *Range) Len() uint64 {
+    if r.Step > 0 && r.Start < r.End {
+        return (uint64(r.End) - uint64(r.Start) - 1) / uint64(r.Step) + 1
+    }
+    if r.Step < 0 && r.Start > r.End {
+        return (uint64(r.Start) - uint64(r.End) - 1) / uint64(-r.Step) + 1
+    }
+    return 0
 }
 
 
@@ -186,3 +464,42 @@ func (h *Hash) Inspect() string {
 type Hashable interface {
     HashKey() HashKey
 }
+
+// Reports whether obj can be a hash key: an integer, boolean or string, or an array or frozen
+// hash made only of those. Arrays and hashes that contain themselves can't be
+func IsHashable(obj Object) bool {
+    return isHashable(obj, map[Object]bool{})
+}
+
+func isHashable(obj Object, inside map[Object]bool) bool {
+    switch obj := obj.(type) {
+    case *Integer, *Boolean, *String:
+        return true
+    case *Array:
+        if inside[obj] {
+            return false
+        }
+        inside[obj] = true
+        defer delete(inside, obj)
+        for _, element := range obj.Elements {
+            if !isHashable(element, inside) {
+                return false
+            }
+        }
+        return true
+    case *Hash:
+        if !obj.Frozen || inside[obj] {
+            return false
+        }
+        inside[obj] = true
+        defer delete(inside, obj)
+        for _, pair := range obj.Pairs {
+            if !isHashable(pair.Value, inside) {
+                return false
+            }
+        }
+        return true
+    default:
+        return false
+    }
+}
@@ -1,6 +1,10 @@
 package object
 
-import "testing"
+import (
+    "math"
+    "math/big"
+    "testing"
+)
 
 func TestStringHashKey(t *testing.T) {
     hello1 := &String{Value: "Hello World"}
@@ -15,3 +19,278 @@ func TestStringHashKey(t *testing.T) {
         t.Errorf("strings with the same content have different hash keys")
     }
 }
+
+func TestIntegerHashKey(t *testing.T) {
+    small := &Integer{Value: 42}
+    big1 := NewBigInteger(new(big.Int).Lsh(big.NewInt(1), 100))
+    big2 := NewBigInteger(new(big.Int).Lsh(big.NewInt(1), 100))
+    negBig := NewBigInteger(new(big.Int).Neg(big1.Big))
+
+    if big1.HashKey() != big2.HashKey() {
+        t.Errorf("big integers with the same value have different hash keys")
+    }
+    if big1.HashKey() == negBig.HashKey() {
+        t.Errorf("big integers with opposite signs have the same hash keys")
+    }
+    if NewBigInteger(big.NewInt(42)).HashKey() != small.HashKey() {
+        t.Errorf("small integer made with NewBigInteger has a different hash key")
+    }
+    if NewBigInteger(big.NewInt(42)).Big != nil {
+        t.Errorf("NewBigInteger did not use a plain int64 for a small value")
+    }
+}
+
+
+func TestRangeLen(t *testing.T) {
+    tests := []struct {
+        r        Range
+        expected uint64
+    }{
+        {Range{Start: 0, End: 10, Step: 1}, 10},
+        {Range{Start: 0, End: 10, Step: 3}, 4},
+        {Range{Start: 10, End: 0, Step: -3}, 4},
+        {Range{Start: 5, End: 5, Step: 1}, 0},
+        {Range{Start: 5, End: 0, Step: 1}, 0},
+        {Range{Start: math.MinInt64, End: math.MaxInt64, Step: 1}, math.MaxUint64},
+    }
+
+    for _, tt := range tests {
+        if tt.r.Len() != tt.expected {
+            t.Errorf("wrong length for %s. expected=%d, got=%d", tt.r.Inspect(), tt.expected, tt.r.Len())
+        }
+    }
+}
+
+
+func TestEqual(t *testing.T) {
+    hash := func(pairs ...Object) *Hash {
+        h := NewHash()
+        for i := 0; i < len(pairs); i += 2 {
+            h.Set(pairs[i], pairs[i+1])
+        }
+        return h
+    }
+    array := func(elements ...Object) *Array {
+        return &Array{Elements: elements}
+    }
+    one := &Integer{Value: 1}
+    big1 := NewBigInteger(new(big.Int).Lsh(big.NewInt(1), 70))
+    big2 := NewBigInteger(new(big.Int).Lsh(big.NewInt(1), 70))
+    fn := &Function{}
+    nan := &Float{Value: math.NaN()}
+
+    tests := []struct {
+        a, b     Object
+        expected bool
+    }{
+        {one, &Integer{Value: 1}, true},
+        {one, &Integer{Value: 2}, false},
+        {one, &Float{Value: 1}, true},
+        {&Float{Value: 1.5}, one, false},
+        {big1, big2, true},
+        {big1, one, false},
+        {big1, &Float{Value: math.Pow(2, 70)}, true},
+        {&String{Value: "a"}, &String{Value: "a"}, true},
+        {&String{Value: "a"}, &String{Value: "b"}, false},
+        {&Boolean{Value: true}, &Boolean{Value: true}, true},
+        {&Null{}, &Null{}, true},
+        {&Null{}, &Boolean{Value: false}, false},
+        {one, &String{Value: "1"}, false},
+        {array(one, &String{Value: "a"}), array(&Integer{Value: 1}, &String{Value: "a"}), true},
+        {array(one), array(one, one), false},
+        {array(array(one)), array(array(&Integer{Value: 2})), false},
+        {hash(&String{Value: "k"}, one), hash(&String{Value: "k"}, &Integer{Value: 1}), true},
+        {hash(&String{Value: "k"}, one), hash(&String{Value: "j"}, one), false},
+        {hash(&String{Value: "k"}, one), hash(), false},
+        {fn, fn, true},
+        {fn, &Function{}, false},
+        {nan, nan, false},
+        {array(nan), array(nan), false},
+        {&Float{Value: 9007199254740992}, &Integer{Value: 9007199254740993}, false},
+    }
+
+    for i, tt := range tests {
+        if Equal(tt.a, tt.b) != tt.expected {
+            t.Errorf("tests[%d]: Equal(%s, %s) wrong. expected=%t", i, tt.a.Inspect(), tt.b.Inspect(), tt.expected)
+        }
+        if Equal(tt.b, tt.a) != tt.expected {
+            t.Errorf("tests[%d]: Equal(%s, %s) wrong. expected=%t", i, tt.b.Inspect(), tt.a.Inspect(), tt.expected)
+        }
+    }
+}
+
+func TestCompareNumbers(t *testing.T) {
+    big1 := NewBigInteger(new(big.Int).Lsh(big.NewInt(1), 70))
+
+    tests := []struct {
+        a, b     Object
+        expected int
+        ok       bool
+    }{
+        {&Integer{Value: 1}, &Integer{Value: 2}, -1, true},
+        {&Integer{Value: 2}, &Float{Value: 1.5}, 1, true},
+        {&Integer{Value: 9007199254740993}, &Float{Value: 9007199254740992}, 1, true},
+        {&Float{Value: 9007199254740992}, &Integer{Value: 9007199254740992}, 0, true},
+        {big1, &Integer{Value: math.MaxInt64}, 1, true},
+        {big1, &Float{Value: math.Inf(1)}, -1, true},
+        {&Float{Value: math.Inf(-1)}, big1, -1, true},
+        {&Float{Value: math.Copysign(0, -1)}, &Integer{Value: 0}, 0, true},
+        {&Float{Value: math.NaN()}, &Integer{Value: 0}, 0, false},
+        {&Float{Value: 1}, &Float{Value: math.NaN()}, 0, false},
+        {&String{Value: "1"}, &Integer{Value: 1}, 0, false},
+    }
+
+    for i, tt := range tests {
+        cmp, ok := CompareNumbers(tt.a, tt.b)
+        if cmp != tt.expected || ok != tt.ok {
+            t.Errorf("tests[%d]: CompareNumbers(%s, %s) wrong. expected=%d, %t got=%d, %t", i, tt.a.Inspect(),
+                tt.b.Inspect(), tt.expected, tt.ok, cmp, ok)
+        }
+    }
+}
+
+func TestEqualCycles(t *testing.T) {
+    a := &Array{Elements: []Object{&Integer{Value: 1}, nil}}
+    a.Elements[1] = a
+    b := &Array{Elements: []Object{&Integer{Value: 1}, nil}}
+    b.Elements[1] = b
+    c := &Array{Elements: []Object{&Integer{Value: 2}, nil}}
+    c.Elements[1] = c
+
+    if !Equal(a, b) {
+        t.Errorf("arrays with the same cycle are not equal")
+    }
+    if Equal(a, c) {
+        t.Errorf("arrays with different elements in a cycle are equal")
+    }
+
+    key := &String{Value: "self"}
+    h1 := &Hash{Pairs: map[HashKey]HashPair{}}
+    h1.Pairs[key.HashKey()] = HashPair{Key: key, Value: h1}
+    h2 := &Hash{Pairs: map[HashKey]HashPair{}}
+    h2.Pairs[key.HashKey()] = HashPair{Key: key, Value: h2}
+    if !Equal(h1, h2) {
+        t.Errorf("hashes with the same cycle are not equal")
+    }
+}
+
+func TestInspectCycles(t *testing.T) {
+    a := &Array{Elements: []Object{&Integer{Value: 1}, nil}}
+    a.Elements[1] = a
+
+    h := NewHash()
+    h.Set(&String{Value: "self"}, h)
+    h.Set(&String{Value: "list"}, a)
+
+    // the same array twice is not a cycle
+    shared := &Array{Elements: []Object{&Integer{Value: 2}}}
+    twice := &Array{Elements: []Object{shared, shared}}
+
+    tests := []struct {
+        obj      Object
+        expected string
+    }{
+        {a, "[1, [...]]"},
+        {h, "{self: {...}, list: [1, [...]]}"},
+        {&Array{Elements: []Object{h}}, "[{self: {...}, list: [1, [...]]}]"},
+        {twice, "[[2], [2]]"},
+    }
+
+    for _, tt := range tests {
+        if got := tt.obj.Inspect(); got != tt.expected {
+            t.Errorf("wrong Inspect. expected=%q, got=%q", tt.expected, got)
+        }
+    }
+}
+
+func TestArrayHashKey(t *testing.T) {
+    a1 := &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}
+    a2 := &Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}}
+    b := &Array{Elements: []Object{&String{Value: "a"}, &Integer{Value: 1}}}
+    nested := &Array{Elements: []Object{&Array{Elements: []Object{&Integer{Value: 1}}}}}
+    flat := &Array{Elements: []Object{&Integer{Value: 1}}}
+
+    if a1.HashKey() != a2.HashKey() {
+        t.Errorf("arrays with same content have different hash keys")
+    }
+    if a1.HashKey() == b.HashKey() {
+        t.Errorf("arrays in a different order have same hash keys")
+    }
+    if nested.HashKey() == flat.HashKey() {
+        t.Errorf("nested array has the same hash key as its element")
+    }
+}
+
+func TestIsHashable(t *testing.T) {
+    cyclic := &Array{Elements: []Object{nil}}
+    cyclic.Elements[0] = cyclic
+    shared := &Array{Elements: []Object{&Integer{Value: 1}}}
+
+    tests := []struct {
+        obj      Object
+        expected bool
+    }{
+        {&Integer{Value: 1}, true},
+        {&String{Value: "a"}, true},
+        {&Boolean{Value: true}, true},
+        {&Null{}, false},
+        {&Float{Value: 1}, false},
+        {&Array{}, true},
+        {&Array{Elements: []Object{&Integer{Value: 1}, &Array{}}}, true},
+        {&Array{Elements: []Object{&Function{}}}, false},
+        {&Array{Elements: []Object{shared, shared}}, true},
+        {cyclic, false},
+        {&Hash{Pairs: map[HashKey]HashPair{}}, false},
+        {&Hash{Pairs: map[HashKey]HashPair{}, Frozen: true}, true},
+    }
+
+    for i, tt := range tests {
+        if IsHashable(tt.obj) != tt.expected {
+            t.Errorf("tests[%d]: IsHashable wrong. expected=%t", i, tt.expected)
+        }
+    }
+}
+
+// Two keys with the same HashKey must both be found
+func TestHashCollisions(t *testing.T) {
+    key := &String{Value: "key"}
+    impostor := &String{Value: "impostor"}
+    h := &Hash{Pairs: map[HashKey]HashPair{}}
+    // pretend impostor hashed to the same value as key and got there first
+    h.Pairs[key.HashKey()] = HashPair{Key: impostor, Value: &Integer{Value: 1}}
+
+    if _, ok := h.Get(key); ok {
+        t.Fatalf("found key that was never set")
+    }
+    h.Set(key, &Integer{Value: 2})
+    h.Set(key, &Integer{Value: 3})
+
+    if len(h.Pairs) != 2 {
+        t.Fatalf("wrong number of pairs. expected=2, got=%d", len(h.Pairs))
+    }
+    pair, ok := h.Get(key)
+    if !ok || pair.Value.(*Integer).Value != 3 {
+        t.Errorf("wrong value for key. got=%v", pair.Value)
+    }
+}
+
+func TestHashOrder(t *testing.T) {
+    h := NewHash()
+    for _, key := range []string{"b", "a", "c", "a"} {
+        h.Set(&String{Value: key}, &String{Value: key + "!"})
+    }
+
+    expected := []string{"b", "a", "c"}
+    pairs := h.OrderedPairs()
+    if len(pairs) != len(expected) {
+        t.Fatalf("wrong number of pairs. expected=%d, got=%d", len(expected), len(pairs))
+    }
+    for i, key := range expected {
+        if pairs[i].Key.(*String).Value != key {
+            t.Errorf("pairs[%d] wrong key. expected=%q, got=%q", i, key, pairs[i].Key.Inspect())
+        }
+    }
+    if h.Inspect() != `{b: b!, a: a!, c: c!}` {
+        t.Errorf("h.Inspect() wrong. got=%q", h.Inspect())
+    }
+}
@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"A-Plus-Plus/token"
+	"fmt"
+)
+
+type Severity int
+
+const (
+    ERROR Severity = iota
+    WARNING
+)
+
+func (s Severity) String() string {
+    switch s {
+    case ERROR:
+        return "error"
+    case WARNING:
+        return "warning"
+    default:
+        return fmt.Sprintf("Severity(%d)", int(s))
+    }
+}
+
+// Codes let tools match on the kind of problem without scraping the message
+const (
+    UNEXPECTED_TOKEN   = "P001" // expectPeek did not get the token it wanted
+    NO_PREFIX_PARSE_FN = "P002" // token cannot start an expression
+    INVALID_INTEGER    = "P003" // integer literal could not be parsed
+    INVALID_FLOAT      = "P004" // float literal could not be parsed
+    BRANCH_OUTSIDE_LOOP = "P006" // break or continue that is not inside a loop
+    INVALID_ASSIGNMENT = "P007" // left side of = is not something that can be assigned to
+    CONST_ASSIGNMENT   = "P008" // a constant is assigned to or declared again
+    LEXICAL_ERROR      = "P009" // the lexer could not read a token, like an unterminated string
+)
+
+// The source range a diagnostic is about. End is exclusive
+type Span struct {
+    Start token.Position
+    End   token.Position
+}
+
+type Diagnostic struct {
+    Severity Severity
+    Code     string
+    Message  string
+    Span     Span
+
+    Expected []token.TokenType // empty when the parser was not looking for anything in particular
+    Found    token.TokenType
+
+    Hint string // optional suggestion on how to fix it
+}
+
+// Same format Errors() has always returned, so older callers keep working
+func (d Diagnostic) String() string {
+    return fmt.Sprintf("%s: %s", d.Span.Start, d.Message)
+}
+
+func spanOf(tok token.Token) Span {
+    if tok.End.IsValid() {
+        return Span{Start: tok.Pos, End: tok.End}
+    }
+    length := len(tok.Literal)
+    if tok.Type == token.STRING {
+        length += 2 // the quotes are not part of the literal
+    }
+    end := tok.Pos
+    end.Offset += length
+    end.Column += length
+    return Span{Start: tok.Pos, End: end}
+}
+
+var closingHints = map[token.TokenType]string {
+    token.RPAREN:   "missing closing ')'",
+    token.RBRACE:   "missing closing '}'",
+    token.RBRACKET: "missing closing ']'",
+    token.INTERP_END: "missing closing '}' of a ${...} in a string",
+}
@@ -5,6 +5,7 @@ import (
 	"A-Plus-Plus/lexer"
 	"A-Plus-Plus/token"
 	"fmt"
+	"math/big"
 	"strconv"
 )
 
@@ -21,7 +22,22 @@ type (
 type Parser struct {
     l *lexer.Lexer
 
-    errors []string
+    diagnostics []Diagnostic
+
+    // Set after the first error in a statement. While it is set, follow-on errors are dropped
+    // until synchronize() finds the start of the next statement
+    panicking bool
+
+    // How many { are open, counting curToken. Lets synchronize() tell a } that closes the
+    // enclosing block from one that belongs to the broken statement
+    depth int
+
+    // How many loops the current statement is inside of, within the innermost function
+    loops int
+
+    // The names declared in each scope we are in, innermost last, and whether they are constants.
+    // Only functions, for loops and catch (e) get a scope, same as environments in the evaluator
+    scopes []map[string]bool
 
     curToken token.Token
     peekToken token.Token
@@ -36,8 +52,10 @@ type Parser struct {
 func New(l *lexer.Lexer) *Parser {
     p := &Parser{
         l: l,
-        errors: []string{},
+        diagnostics: []Diagnostic{},
+        scopes: []map[string]bool{{}},
     }
+    l.SetErrorHandler(p.lexerError)
 
     // Read 2 tokens so curToken and peekToken are both populated
     p.nextToken()
@@ -48,12 +66,16 @@ func New(l *lexer.Lexer) *Parser {
     // associate function p.parseIdentifier with token type IDENT
     p.registerPrefix(token.IDENT, p.parseIdentifier)
     p.registerPrefix(token.INT, p.parseIntegerLiteral)
+    p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
     p.registerPrefix(token.BANG, p.parsePrefixExpression)
     p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+    p.registerPrefix(token.BIT_NOT, p.parsePrefixExpression)
+    p.registerPrefix(token.INTERP_START, p.parseInterpolatedString)
     p.registerPrefix(token.TRUE, p.parseBoolean)
     p.registerPrefix(token.FALSE, p.parseBoolean)
     p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
     p.registerPrefix(token.IF, p.parseIfExpression)
+    p.registerPrefix(token.TRY, p.parseTryExpression)
     p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
     p.registerPrefix(token.STRING, p.parseStringLiteral)
     p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
@@ -65,10 +87,26 @@ func New(l *lexer.Lexer) *Parser {
     p.registerInfix(token.MINUS, p.parseInfixExpression)
     p.registerInfix(token.SLASH, p.parseInfixExpression)
     p.registerInfix(token.ASTERISK, p.parseInfixExpression)
+    p.registerInfix(token.PERCENT, p.parseInfixExpression)
+    p.registerInfix(token.AND, p.parseInfixExpression)
+    p.registerInfix(token.OR, p.parseInfixExpression)
     p.registerInfix(token.EQ, p.parseInfixExpression)
     p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
     p.registerInfix(token.LT, p.parseInfixExpression)
     p.registerInfix(token.GT, p.parseInfixExpression)
+    p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+    p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+    p.registerInfix(token.POWER, p.parseInfixExpression)
+    p.registerInfix(token.BIT_AND, p.parseInfixExpression)
+    p.registerInfix(token.BIT_OR, p.parseInfixExpression)
+    p.registerInfix(token.BIT_XOR, p.parseInfixExpression)
+    p.registerInfix(token.SHIFT_LEFT, p.parseInfixExpression)
+    p.registerInfix(token.SHIFT_RIGHT, p.parseInfixExpression)
+    p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+    p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+    p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+    p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+    p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
     p.registerInfix(token.LPAREN, p.parseCallExpression)
     p.registerInfix(token.LBRACKET, p.parseIndexExpression)
     return p
@@ -88,6 +126,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
         p.nextToken()
         value := p.parseExpression(LOWEST)
         hash.Pairs[key] = value
+        hash.Keys = append(hash.Keys, key)
 
         if !p.peekTokenIs(token.RBRACE) && ! p.expectPeek(token.COMMA) {
             return nil
@@ -100,15 +139,45 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 }
 
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-    exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+    tok := p.curToken
+    var index ast.Expression
+    if !p.peekTokenIs(token.COLON) {
+        p.nextToken()
+        index = p.parseExpression(LOWEST)
+    }
+    if p.peekTokenIs(token.COLON) {
+        return p.parseSliceExpression(tok, left, index)
+    }
+    if !p.expectPeek(token.RBRACKET) {
+        return nil
+    }
+    return &ast.IndexExpression{Token: tok, Left: left, Index: index}
+}
+
+// Called on the token before the first colon, with start nil if it was left out
+func (p *Parser) parseSliceExpression(tok token.Token, left, start ast.Expression) ast.Expression {
+    exp := &ast.SliceExpression{Token: tok, Left: left, Start: start}
     p.nextToken()
-    exp.Index = p.parseExpression(LOWEST)
+    exp.End = p.parseSliceBound()
+    if p.peekTokenIs(token.COLON) {
+        p.nextToken()
+        exp.Step = p.parseSliceBound()
+    }
     if !p.expectPeek(token.RBRACKET) {
         return nil
     }
     return exp
 }
 
+// The expression after a colon in a slice, or nil if there is none
+func (p *Parser) parseSliceBound() ast.Expression {
+    if p.peekTokenIs(token.COLON) || p.peekTokenIs(token.RBRACKET) {
+        return nil
+    }
+    p.nextToken()
+    return p.parseExpression(LOWEST)
+}
+
 func (p *Parser) parseArrayLiteral() ast.Expression {
     array := &ast.ArrayLiteral{Token: p.curToken}
     array.Elements = p.parseExpressionList(token.RBRACKET)
@@ -124,7 +193,7 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
     p.nextToken()
     list = append(list, p.parseExpression(LOWEST))
 
-    for p.peekTokenIs(token.COMMA) {
+    for !p.panicking && p.peekTokenIs(token.COMMA) {
         p.nextToken()
         p.nextToken()
         list = append(list, p.parseExpression(LOWEST))
@@ -176,7 +245,13 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
         return nil
     }
 
+    // break can't leave a function to get to a loop it is called from
+    loops := p.loops
+    p.loops = 0
+    p.openScope(lit.Parameters...)
     lit.Body = p.parseBlockStatement()
+    p.closeScope()
+    p.loops = loops
 
     return lit
 }
@@ -241,6 +316,57 @@ func (p *Parser) parseIfExpression() ast.Expression {
     return expression
 }
 
+func (p *Parser) parseTryExpression() ast.Expression {
+    expression := &ast.TryExpression{Token: p.curToken}
+
+    if !p.expectPeek(token.LBRACE) {
+        return nil
+    }
+    expression.Block = p.parseBlockStatement()
+
+    if p.peekTokenIs(token.CATCH) {
+        p.nextToken()
+
+        // the parameter is optional, catch { ... } just swallows the error
+        if p.peekTokenIs(token.LPAREN) {
+            p.nextToken()
+            if !p.expectPeek(token.IDENT) {
+                return nil
+            }
+            expression.Param = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+            if !p.expectPeek(token.RPAREN) {
+                return nil
+            }
+        }
+
+        if !p.expectPeek(token.LBRACE) {
+            return nil
+        }
+        if expression.Param != nil {
+            p.openScope(expression.Param)
+            expression.Catch = p.parseBlockStatement()
+            p.closeScope()
+        } else {
+            expression.Catch = p.parseBlockStatement()
+        }
+    }
+
+    if p.peekTokenIs(token.FINALLY) {
+        p.nextToken()
+        if !p.expectPeek(token.LBRACE) {
+            return nil
+        }
+        expression.Finally = p.parseBlockStatement()
+    }
+
+    if expression.Catch == nil && expression.Finally == nil {
+        p.peekError(token.CATCH)
+        return nil
+    }
+
+    return expression
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
     block := &ast.BlockStatement{Token: p.curToken}
     block.Statements = []ast.Statement{}
@@ -248,13 +374,23 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
     p.nextToken()
 
     for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+        depth := p.depth
         stmt := p.parseStatement()
+        if p.panicking {
+            // drop the broken statement, the error is already reported
+            p.synchronize(depth)
+            continue
+        }
         if stmt != nil {
             block.Statements = append(block.Statements, stmt)
         }
         p.nextToken()
     }
 
+    if p.curTokenIs(token.EOF) {
+        p.unexpectedTokenError(token.RBRACE, p.curToken)
+    }
+
     return block
 }
 
@@ -284,19 +420,147 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
     p.infixParseFns[tokenType] = fn
 }
 
+// The diagnostics rendered as plain strings
 func (p *Parser) Errors() []string {
-    return p.errors
+    errors := []string{}
+    for _, d := range p.diagnostics {
+        if d.Severity == ERROR {
+            errors = append(errors, d.String())
+        }
+    }
+    return errors
+}
+
+func (p *Parser) Diagnostics() []Diagnostic {
+    return p.diagnostics
+}
+
+func (p *Parser) addError(d Diagnostic) {
+    if p.panicking {
+        return
+    }
+    p.panicking = true
+
+    if n := len(p.diagnostics); n > 0 {
+        last := p.diagnostics[n-1]
+        if last.Code == d.Code && last.Span.Start == d.Span.Start && last.Message == d.Message {
+            return
+        }
+    }
+
+    d.Severity = ERROR
+    p.diagnostics = append(p.diagnostics, d)
+}
+
+// The lexer reports problems with single tokens, which don't need recovering from either. They are
+// found while the token is peekToken, so even a statement that is already broken gets them
+func (p *Parser) lexerError(pos token.Position, msg string) {
+    p.semanticError(Diagnostic{
+        Code:    LEXICAL_ERROR,
+        Message: msg,
+        Span:    Span{Start: pos, End: pos},
+        Found:   token.ILLEGAL,
+    })
+}
+
+func (p *Parser) lexerReported(tok token.Token) bool {
+    for i := len(p.diagnostics) - 1; i >= 0; i-- {
+        d := p.diagnostics[i]
+        if d.Code == LEXICAL_ERROR && d.Span.Start == tok.Pos {
+            return true
+        }
+    }
+    return false
+}
+
+// For errors in code that parsed fine, so there is nothing to recover from
+func (p *Parser) semanticError(d Diagnostic) {
+    d.Severity = ERROR
+    p.diagnostics = append(p.diagnostics, d)
+}
+
+// names can contain nil, for optional ones like the value in for (k, v in x)
+func (p *Parser) openScope(names ...*ast.Identifier) {
+    scope := map[string]bool{}
+    for _, name := range names {
+        if name != nil {
+            scope[name.Value] = false
+        }
+    }
+    p.scopes = append(p.scopes, scope)
+}
+
+func (p *Parser) closeScope() {
+    p.scopes = p.scopes[:len(p.scopes)-1]
+}
+
+// Reports whether name refers to a constant declared earlier. Names declared later, like globals
+// used inside a function defined before them, are left for the evaluator to check
+func (p *Parser) isConstant(name string) bool {
+    for i := len(p.scopes) - 1; i >= 0; i-- {
+        if constant, ok := p.scopes[i][name]; ok {
+            return constant
+        }
+    }
+    return false
+}
+
+// Skip tokens until curToken is the start of the next statement, or the } closing the current block.
+// depth is p.depth from when the broken statement started, anything deeper is still part of it
+func (p *Parser) synchronize(depth int) {
+    p.panicking = false
+
+    for !p.curTokenIs(token.EOF) {
+        if p.curTokenIs(token.RBRACE) && p.depth < depth {
+            return
+        }
+        if p.depth != depth {
+            p.nextToken()
+            continue
+        }
+        if p.curTokenIs(token.SEMICOLON) {
+            p.nextToken()
+            return
+        }
+        switch p.peekToken.Type {
+        case token.LET, token.CONST, token.RETURN, token.RBRACE:
+            p.nextToken()
+            return
+        }
+        p.nextToken()
+    }
 }
 
 // Print error when peek token is not what we expect
 func (p *Parser) peekError(t token.TokenType) {
-    msg := fmt.Sprintf("Expected next token to be %s, got %s instead", t, p.peekToken.Type)
-    p.errors = append(p.errors, msg)
+    if p.peekTokenIs(token.ILLEGAL) && p.lexerReported(p.peekToken) {
+        p.panicking = true
+        return
+    }
+    p.unexpectedTokenError(t, p.peekToken)
+}
+
+func (p *Parser) unexpectedTokenError(t token.TokenType, got token.Token) {
+    p.addError(Diagnostic{
+        Code:     UNEXPECTED_TOKEN,
+        Message:  fmt.Sprintf("Expected next token to be %s, got %s instead", t, got.Type),
+        Span:     spanOf(got),
+        Expected: []token.TokenType{t},
+        Found:    got.Type,
+        Hint:     closingHints[t],
+    })
 }
 
 func (p *Parser) nextToken() {
     p.curToken = p.peekToken
     p.peekToken = p.l.NextToken()
+
+    switch p.curToken.Type {
+    case token.LBRACE:
+        p.depth += 1
+    case token.RBRACE:
+        p.depth -= 1
+    }
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -304,7 +568,16 @@ func (p *Parser) ParseProgram() *ast.Program {
     program.Statements = []ast.Statement{}
 
     for !p.curTokenIs(token.EOF) {
+        depth := p.depth
         stmt := p.parseStatement()
+        if p.panicking {
+            p.synchronize(depth)
+            // there is no block to close at the top level, so a } we stopped at is just stray
+            if p.curTokenIs(token.RBRACE) {
+                p.nextToken()
+            }
+            continue
+        }
         if stmt != nil {
             program.Statements = append(program.Statements, stmt)
         }
@@ -316,10 +589,18 @@ func (p *Parser) ParseProgram() *ast.Program {
 
 func (p *Parser) parseStatement() ast.Statement {
     switch p.curToken.Type {
-    case token.LET:
+    case token.LET, token.CONST:
         return p.parseLetStatement()
     case token.RETURN:
         return p.parseReturnStatement()
+    case token.THROW:
+        return p.parseThrowStatement()
+    case token.WHILE:
+        return p.parseWhileStatement()
+    case token.FOR:
+        return p.parseForStatement()
+    case token.BREAK, token.CONTINUE:
+        return p.parseBranchStatement()
     default:
         return p.parseExpressionStatement()
     }
@@ -328,25 +609,52 @@ func (p *Parser) parseStatement() ast.Statement {
 const (
     _ int = iota // incrementing numbers as values. blank '_' takes value 0, the consts below take 1 to 7
     LOWEST
+    ASSIGN          // = or +=
+    LOGICAL_OR      // ||
+    LOGICAL_AND     // &&
     EQUALS          // ==
     LESSGREATER     // > or <
+    BIT_OR          // |
+    BIT_XOR         // ^
+    BIT_AND         // &
+    SHIFT           // << or >>
     SUM             // +
     PRODUCT         // *
     PREFIX          // -X or !X
+    POWER           // **, so -2 ** 2 is -(2 ** 2)
     CALL            // myFunction(X)
     INDEX           // array[index]
 )
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-    msg := fmt.Sprintf("no prefix parse function for %s found", t)
-    p.errors = append(p.errors, msg)
+    // the lexer already said what is wrong with it
+    if t == token.ILLEGAL && p.lexerReported(p.curToken) {
+        p.panicking = true
+        return
+    }
+    hint := ""
+    switch t {
+    case token.RPAREN, token.RBRACE, token.RBRACKET:
+        hint = fmt.Sprintf("unbalanced '%s'", p.curToken.Literal)
+    case token.EOF:
+        hint = "the input ended in the middle of an expression"
+    case token.INTERP_MID, token.INTERP_END:
+        hint = "empty ${} in a string"
+    }
+    p.addError(Diagnostic{
+        Code:    NO_PREFIX_PARSE_FN,
+        Message: fmt.Sprintf("no prefix parse function for %s found", t),
+        Span:    spanOf(p.curToken),
+        Found:   t,
+        Hint:    hint,
+    })
 }
 
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
     stmt := &ast.ExpressionStatement {Token: p.curToken}
     stmt.Expression = p.parseExpression(LOWEST)
 
-    if p.peekTokenIs(token.SEMICOLON) {
+    if !p.panicking && p.peekTokenIs(token.SEMICOLON) {
         p.nextToken()
     }
 
@@ -362,7 +670,8 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
     }
     leftExp := prefix()
 
-    for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
+    // after an error, leave the rest to synchronize() instead of building on a broken expression
+    for !p.panicking && !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
         infixFn := p.infixParseFns[p.peekToken.Type]
         if infixFn == nil {
             return leftExp
@@ -384,6 +693,118 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 
     stmt.ReturnValue = p.parseExpression(LOWEST)
 
+    if !p.panicking && p.peekTokenIs(token.SEMICOLON) {
+        p.nextToken()
+    }
+
+    return stmt
+}
+
+
+
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+    stmt := &ast.ThrowStatement{Token: p.curToken}
+
+    p.nextToken()
+
+    stmt.Value = p.parseExpression(LOWEST)
+
+    if !p.panicking && p.peekTokenIs(token.SEMICOLON) {
+        p.nextToken()
+    }
+
+    return stmt
+}
+
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+    stmt := &ast.WhileStatement{Token: p.curToken}
+
+    if !p.expectPeek(token.LPAREN) {
+        return nil
+    }
+
+    p.nextToken()
+    stmt.Condition = p.parseExpression(LOWEST)
+
+    if !p.expectPeek(token.RPAREN) {
+        return nil
+    }
+
+    if !p.expectPeek(token.LBRACE) {
+        return nil
+    }
+
+    p.loops += 1
+    stmt.Body = p.parseBlockStatement()
+    p.loops -= 1
+
+    if !p.panicking && p.peekTokenIs(token.SEMICOLON) {
+        p.nextToken()
+    }
+
+    return stmt
+}
+
+func (p *Parser) parseForStatement() *ast.ForStatement {
+    stmt := &ast.ForStatement{Token: p.curToken}
+
+    if !p.expectPeek(token.LPAREN) {
+        return nil
+    }
+
+    if !p.expectPeek(token.IDENT) {
+        return nil
+    }
+    stmt.Key = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+    if p.peekTokenIs(token.COMMA) {
+        p.nextToken()
+        if !p.expectPeek(token.IDENT) {
+            return nil
+        }
+        stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+    }
+
+    if !p.expectPeek(token.IN) {
+        return nil
+    }
+
+    p.nextToken()
+    stmt.Iterable = p.parseExpression(LOWEST)
+
+    if !p.expectPeek(token.RPAREN) {
+        return nil
+    }
+
+    if !p.expectPeek(token.LBRACE) {
+        return nil
+    }
+
+    p.loops += 1
+    p.openScope(stmt.Key, stmt.Value)
+    stmt.Body = p.parseBlockStatement()
+    p.closeScope()
+    p.loops -= 1
+
+    if !p.panicking && p.peekTokenIs(token.SEMICOLON) {
+        p.nextToken()
+    }
+
+    return stmt
+}
+
+func (p *Parser) parseBranchStatement() *ast.BranchStatement {
+    stmt := &ast.BranchStatement{Token: p.curToken}
+
+    if p.loops == 0 {
+        p.semanticError(Diagnostic{
+            Code:     BRANCH_OUTSIDE_LOOP,
+            Message:  fmt.Sprintf("%s outside of a loop", stmt.Token.Literal),
+            Span:     spanOf(stmt.Token),
+            Found:    stmt.Token.Type,
+        })
+    }
+
     if p.peekTokenIs(token.SEMICOLON) {
         p.nextToken()
     }
@@ -402,13 +823,29 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
     stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
+    scope := p.scopes[len(p.scopes)-1]
+    if scope[stmt.Name.Value] {
+        p.semanticError(Diagnostic{
+            Code:    CONST_ASSIGNMENT,
+            Message: fmt.Sprintf("cannot redeclare constant %s", stmt.Name.Value),
+            Span:    spanOf(stmt.Name.Token),
+            Found:   stmt.Name.Token.Type,
+        })
+    } else {
+        scope[stmt.Name.Value] = stmt.Token.Type == token.CONST
+    }
+
     if !p.expectPeek(token.ASSIGN) {
         return nil
     }
     p.nextToken()
     stmt.Value = p.parseExpression(LOWEST)
 
-    if p.peekTokenIs(token.SEMICOLON) {
+    if fl, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+        fl.Name = stmt.Name.Value
+    }
+
+    if !p.panicking && p.peekTokenIs(token.SEMICOLON) {
         p.nextToken()
     }
 
@@ -437,10 +874,118 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 func (p *Parser) parseIntegerLiteral() ast.Expression {
     lit := &ast.IntegerLiteral{Token: p.curToken}
 
-    value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+    base, digits, msg, at := splitIntegerLiteral(p.curToken.Literal)
+    if msg != "" {
+        // point at the offending char rather than the whole literal
+        start := p.curToken.Pos
+        start.Offset += at
+        start.Column += at
+        end := start
+        end.Offset += 1
+        end.Column += 1
+        p.addError(Diagnostic{
+            Code:    INVALID_INTEGER,
+            Message: msg,
+            Span:    Span{Start: start, End: end},
+            Found:   p.curToken.Type,
+        })
+        return nil
+    }
+
+    value, err := strconv.ParseInt(digits, base, 64)
+    if err == nil {
+        lit.Value = value
+        return lit
+    }
+
+    // too big for an int64, so it is a big integer, like the result of arithmetic that overflows
+    bigValue, ok := new(big.Int).SetString(digits, base)
+    if !ok {
+        p.addError(Diagnostic{
+            Code:    INVALID_INTEGER,
+            Message: fmt.Sprintf("could not parse %q as integer", p.curToken.Literal),
+            Span:    spanOf(p.curToken),
+            Found:   p.curToken.Type,
+        })
+        return nil
+    }
+    lit.Big = bigValue
+
+    return lit
+}
+
+
+var integerBaseNames = map[int]string {
+    2:  "binary",
+    8:  "octal",
+    10: "decimal",
+    16: "hexadecimal",
+}
+
+// Works out the base of an integer literal and strips the prefix and underscores, ready for
+// strconv. If the literal is malformed, msg says why and at is the offset of the offending char
+func splitIntegerLiteral(lit string) (base int, digits string, msg string, at int) {
+    base, start := 10, 0
+    if len(lit) >= 2 && lit[0] == '0' {
+        switch lit[1] {
+        case 'x', 'X':
+            base, start = 16, 2
+        case 'o', 'O':
+            base, start = 8, 2
+        case 'b', 'B':
+            base, start = 2, 2
+        }
+    }
+    name := integerBaseNames[base]
+
+    out := make([]byte, 0, len(lit))
+    for i := start; i < len(lit); i++ {
+        ch := lit[i]
+        if ch == '_' {
+            // an underscore may follow the prefix, but otherwise has to sit between two digits
+            if lit[i-1] == '_' || i == len(lit)-1 {
+                return 0, "", "'_' must separate successive digits", i
+            }
+            continue
+        }
+        if digitValue(ch) >= base {
+            return 0, "", fmt.Sprintf("invalid digit %q in %s literal", ch, name), i
+        }
+        out = append(out, ch)
+    }
+
+    if len(out) == 0 {
+        return 0, "", fmt.Sprintf("%s literal has no digits", name), start
+    }
+    return base, string(out), "", 0
+}
+
+func digitValue(ch byte) int {
+    switch {
+    case '0' <= ch && ch <= '9':
+        return int(ch - '0')
+    case 'a' <= ch && ch <= 'f':
+        return int(ch - 'a' + 10)
+    case 'A' <= ch && ch <= 'F':
+        return int(ch - 'A' + 10)
+    default:
+        return 16 // too big for every base we support
+    }
+}
+
+
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+    lit := &ast.FloatLiteral{Token: p.curToken}
+
+    value, err := strconv.ParseFloat(p.curToken.Literal, 64)
     if err != nil {
-        msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-        p.errors = append(p.errors, msg)
+        p.addError(Diagnostic{
+            Code:    INVALID_FLOAT,
+            Message: fmt.Sprintf("could not parse %q as float", p.curToken.Literal),
+            Span:    spanOf(p.curToken),
+            Found:   p.curToken.Type,
+        })
         return nil
     }
 
@@ -466,16 +1011,32 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 
 
 var precedences = map[token.TokenType]int {
-    token.EQ:       EQUALS,
-    token.NOT_EQ:   EQUALS,
-    token.LT:       LESSGREATER,
-    token.GT:       LESSGREATER,
-    token.PLUS:     SUM,
-    token.MINUS:    SUM,
-    token.SLASH:    PRODUCT,
-    token.ASTERISK: PRODUCT,
-    token.LPAREN:   CALL,
-    token.LBRACKET: INDEX,
+    token.ASSIGN:          ASSIGN,
+    token.PLUS_ASSIGN:     ASSIGN,
+    token.MINUS_ASSIGN:    ASSIGN,
+    token.ASTERISK_ASSIGN: ASSIGN,
+    token.SLASH_ASSIGN:    ASSIGN,
+    token.OR:              LOGICAL_OR,
+    token.AND:             LOGICAL_AND,
+    token.EQ:              EQUALS,
+    token.NOT_EQ:          EQUALS,
+    token.LT:              LESSGREATER,
+    token.GT:              LESSGREATER,
+    token.LT_EQ:           LESSGREATER,
+    token.GT_EQ:           LESSGREATER,
+    token.BIT_OR:          BIT_OR,
+    token.BIT_XOR:         BIT_XOR,
+    token.BIT_AND:         BIT_AND,
+    token.SHIFT_LEFT:      SHIFT,
+    token.SHIFT_RIGHT:     SHIFT,
+    token.PLUS:            SUM,
+    token.MINUS:           SUM,
+    token.SLASH:           PRODUCT,
+    token.ASTERISK:        PRODUCT,
+    token.PERCENT:         PRODUCT,
+    token.POWER:           POWER,
+    token.LPAREN:          CALL,
+    token.LBRACKET:        INDEX,
 }
 
 func (p *Parser) peekPrecedence() int {
@@ -503,12 +1064,52 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
     }
 
     precedence := p.curPrecedence()
+    // ** is right associative, 2 ** 3 ** 2 is 2 ** (3 ** 2)
+    if expression.Operator == "**" {
+        precedence -= 1
+    }
     p.nextToken()
     expression.Right = p.parseExpression(precedence)
 
     return expression
 }
 
+// Assignment is right associative, so a = b = 1 sets both
+func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
+    expression := &ast.AssignExpression{
+        Token:    p.curToken,
+        Target:   left,
+        Operator: p.curToken.Literal,
+    }
+
+    switch left := left.(type) {
+    case *ast.Identifier:
+        if p.isConstant(left.Value) {
+            p.semanticError(Diagnostic{
+                Code:    CONST_ASSIGNMENT,
+                Message: fmt.Sprintf("cannot assign to constant %s", left.Value),
+                Span:    spanOf(left.Token),
+                Found:   left.Token.Type,
+            })
+        }
+    case *ast.IndexExpression:
+    default:
+        p.addError(Diagnostic{
+            Code:    INVALID_ASSIGNMENT,
+            Message: fmt.Sprintf("cannot assign to %s", left.String()),
+            Span:    spanOf(p.curToken),
+            Found:   p.curToken.Type,
+            Hint:    "only names and index expressions like a[i] can be assigned to",
+        })
+        return nil
+    }
+
+    p.nextToken()
+    expression.Value = p.parseExpression(ASSIGN - 1)
+
+    return expression
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
     return &ast.Boolean{
         Token: p.curToken,
@@ -520,3 +1121,23 @@ func (p *Parser) parseBoolean() ast.Expression {
 func (p *Parser) parseStringLiteral() ast.Expression {
     return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
+
+func (p *Parser) parseInterpolatedString() ast.Expression {
+    str := &ast.InterpolatedString{Token: p.curToken}
+    str.Parts = append(str.Parts, p.parseStringLiteral())
+
+    for !p.curTokenIs(token.INTERP_END) {
+        p.nextToken()
+        str.Parts = append(str.Parts, p.parseExpression(LOWEST))
+
+        if !p.peekTokenIs(token.INTERP_MID) && !p.expectPeek(token.INTERP_END) {
+            return nil
+        }
+        if p.peekTokenIs(token.INTERP_MID) {
+            p.nextToken()
+        }
+        str.Parts = append(str.Parts, p.parseStringLiteral())
+    }
+
+    return str
+}
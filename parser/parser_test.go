@@ -3,6 +3,7 @@ package parser
 import (
 	"A-Plus-Plus/ast"
 	"A-Plus-Plus/lexer"
+	"A-Plus-Plus/token"
 	"testing"
     "fmt"
 )
@@ -39,6 +40,151 @@ func TestIntegerLiteralExpression(t *testing.T) {
     }
 }
 
+func TestIntegerLiteralBases(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected int64
+    }{
+        {"0x1F", 31},
+        {"0XfF", 255},
+        {"0o17", 15},
+        {"0b1010", 10},
+        {"1_000_000", 1000000},
+        {"0x_ff_ff", 65535},
+        {"017", 17},
+        {"9223372036854775807", 9223372036854775807},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        stmt := program.Statements[0].(*ast.ExpressionStatement)
+        literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+        if !ok {
+            t.Fatalf("exp not *ast.IntegerLiteral. got %T instead", stmt.Expression)
+        }
+        if literal.Value != tt.expected {
+            t.Errorf("%q: literal.Value not %d, got %d instead", tt.input, tt.expected, literal.Value)
+        }
+    }
+}
+
+func TestBigIntegerLiterals(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected string
+    }{
+        {"9223372036854775808", "9223372036854775808"},
+        {"100000000000000000000", "100000000000000000000"},
+        {"1_000_000_000_000_000_000_000", "1000000000000000000000"},
+        {"0xffffffffffffffff", "18446744073709551615"},
+        {"0b1_0000000000000000000000000000000000000000000000000000000000000000", "18446744073709551616"},
+        {"0o1777777777777777777777", "18446744073709551615"},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        stmt := program.Statements[0].(*ast.ExpressionStatement)
+        literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+        if !ok {
+            t.Fatalf("exp not *ast.IntegerLiteral. got %T instead", stmt.Expression)
+        }
+        if literal.Big == nil || literal.Big.String() != tt.expected {
+            t.Errorf("%q: literal.Big not %s, got %v instead", tt.input, tt.expected, literal.Big)
+        }
+        // printed back as written, so it can be parsed again
+        if literal.String() != tt.input {
+            t.Errorf("%q: literal.String() wrong, got %q", tt.input, literal.String())
+        }
+    }
+}
+
+func TestInvalidIntegerLiterals(t *testing.T) {
+    tests := []struct {
+        input          string
+        expectedCode   string
+        expectedError  string
+    }{
+        {"0x1g", INVALID_INTEGER, "1:4: invalid digit 'g' in hexadecimal literal"},
+        {"0o78", INVALID_INTEGER, "1:4: invalid digit '8' in octal literal"},
+        {"0b102", INVALID_INTEGER, "1:5: invalid digit '2' in binary literal"},
+        {"0x", INVALID_INTEGER, "1:3: hexadecimal literal has no digits"},
+        {"1__000", INVALID_INTEGER, "1:3: '_' must separate successive digits"},
+        {"let x = 1_;", INVALID_INTEGER, "1:10: '_' must separate successive digits"},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        p.ParseProgram()
+
+        diagnostics := p.Diagnostics()
+        if len(diagnostics) != 1 {
+            t.Errorf("%q: expected 1 diagnostic, got %d %q", tt.input, len(diagnostics), p.Errors())
+            continue
+        }
+        if diagnostics[0].Code != tt.expectedCode {
+            t.Errorf("%q: diagnostic code not %s, got %s", tt.input, tt.expectedCode, diagnostics[0].Code)
+        }
+        if diagnostics[0].String() != tt.expectedError {
+            t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expectedError, diagnostics[0].String())
+        }
+    }
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected float64
+    }{
+        {"3.14;", 3.14},
+        {"1.5e-3", 0.0015},
+        {"2E3", 2000},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        if len(program.Statements) != 1 {
+            t.Fatalf("program has not enough statements. go %d instead", len(program.Statements))
+        }
+
+        stmt := program.Statements[0].(*ast.ExpressionStatement)
+        literal, ok := stmt.Expression.(*ast.FloatLiteral)
+        if !ok {
+            t.Fatalf("exp not *ast.FloatLiteral. got %T instead", stmt.Expression)
+        }
+
+        if literal.Value != tt.expected {
+            t.Errorf("literal.Value not %g, got %g instead", tt.expected, literal.Value)
+        }
+    }
+}
+
+func TestInvalidFloatLiteral(t *testing.T) {
+    l := lexer.New("1e999")
+    p := New(l)
+    p.ParseProgram()
+
+    diagnostics := p.Diagnostics()
+    if len(diagnostics) != 1 {
+        t.Fatalf("expected 1 diagnostic, got %d", len(diagnostics))
+    }
+    if diagnostics[0].Code != INVALID_FLOAT {
+        t.Errorf("diagnostic code not %s, got %s", INVALID_FLOAT, diagnostics[0].Code)
+    }
+}
+
 func TestIdentifierExpression(t *testing.T) {
     input := "foobar";
 
@@ -408,6 +554,62 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
             "add(a * b[2], b[1], 2 * [1, 2][1])",
             "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
         },
+        {
+            "-1.5 * 2 + 0.5",
+            "(((-1.5) * 2) + 0.5)",
+        },
+        {
+            "a + b % c * d",
+            "(a + ((b % c) * d))",
+        },
+        {
+            "a || b && c",
+            "(a || (b && c))",
+        },
+        {
+            "a && b || c && d",
+            "((a && b) || (c && d))",
+        },
+        {
+            "a == b && !c || d < e",
+            "(((a == b) && (!c)) || (d < e))",
+        },
+        {
+            "x = a || b",
+            "x = (a || b)",
+        },
+        {
+            "a <= b == b >= c",
+            "((a <= b) == (b >= c))",
+        },
+        {
+            "a | b ^ c & d",
+            "(a | (b ^ (c & d)))",
+        },
+        {
+            "a & b == c",
+            "((a & b) == c)",
+        },
+        {
+            "1 << 2 + 3 < x",
+            "((1 << (2 + 3)) < x)",
+        },
+        {
+            "a ** b ** c",
+            "(a ** (b ** c))",
+        },
+        {
+            "-a ** b",
+            "(-(a ** b))",
+        },
+        {
+            "a * b ** -c",
+            "(a * (b ** (-c)))",
+        },
+        {
+            "~a & b",
+            "((~a) & b)",
+        },
     }
 
     for _, tt := range tests {
@@ -876,3 +1078,627 @@ func TestParsingLiteralsWithExpressions(t *testing.T) {
         testFunc(value)
     }
 }
+
+
+func TestErrorPositions(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected string
+    }{
+        {"let x 5;", "1:7: Expected next token to be =, got INT instead"},
+        {"let x = 5;\nlet y = );", "2:9: no prefix parse function for ) found"},
+        {"\n\n  add(1, 2", "3:11: Expected next token to be ), got EOF instead"},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) == 0 {
+            t.Errorf("expected parser errors for %q, got none", tt.input)
+            continue
+        }
+        if errors[0] != tt.expected {
+            t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errors[0])
+        }
+    }
+}
+
+
+func TestNodePositions(t *testing.T) {
+    input := "let a = 1;\nfoo(a) + b[2];"
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    if len(program.Statements) != 2 {
+        t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+    }
+
+    stmt := program.Statements[1].(*ast.ExpressionStatement)
+    infix := stmt.Expression.(*ast.InfixExpression)
+    call := infix.Left.(*ast.CallExpression)
+    index := infix.Right.(*ast.IndexExpression)
+
+    tests := []struct {
+        node   ast.Node
+        line   int
+        column int
+    }{
+        {program, 1, 1},
+        {program.Statements[0].(*ast.LetStatement).Value, 1, 9},
+        {infix, 2, 1},
+        {call, 2, 1},
+        {call.Arguments[0], 2, 5},
+        {index, 2, 10},
+        {index.Index, 2, 12},
+    }
+
+    for i, tt := range tests {
+        pos := tt.node.Pos()
+        if pos.Line != tt.line || pos.Column != tt.column {
+            t.Errorf("tests[%d]: wrong position for %q. expected=%d:%d, got=%d:%d", i, tt.node.String(),
+                tt.line, tt.column, pos.Line, pos.Column)
+        }
+    }
+}
+
+
+func TestDiagnostics(t *testing.T) {
+    input := "let x = add(1, 2;"
+    l := lexer.New(input)
+    p := New(l)
+    p.ParseProgram()
+
+    diagnostics := p.Diagnostics()
+    if len(diagnostics) == 0 {
+        t.Fatalf("expected diagnostics, got none")
+    }
+
+    d := diagnostics[0]
+    if d.Severity != ERROR {
+        t.Errorf("d.Severity not ERROR. got=%s", d.Severity)
+    }
+    if d.Code != UNEXPECTED_TOKEN {
+        t.Errorf("d.Code not %s. got=%s", UNEXPECTED_TOKEN, d.Code)
+    }
+    if len(d.Expected) != 1 || d.Expected[0] != token.RPAREN {
+        t.Errorf("d.Expected not [%s]. got=%v", token.RPAREN, d.Expected)
+    }
+    if d.Found != token.SEMICOLON {
+        t.Errorf("d.Found not %s. got=%s", token.SEMICOLON, d.Found)
+    }
+    if d.Span.Start.Column != 17 || d.Span.End.Column != 18 {
+        t.Errorf("d.Span wrong. expected columns 17-18, got=%d-%d", d.Span.Start.Column, d.Span.End.Column)
+    }
+    if d.Hint != "missing closing ')'" {
+        t.Errorf("d.Hint wrong. got=%q", d.Hint)
+    }
+    if p.Errors()[0] != d.String() {
+        t.Errorf("Errors() and Diagnostics() disagree. got=%q and %q", p.Errors()[0], d.String())
+    }
+    if d.String() != "1:17: Expected next token to be ), got ; instead" {
+        t.Errorf("d.String() wrong. got=%q", d.String())
+    }
+}
+
+
+func TestErrorRecovery(t *testing.T) {
+    tests := []struct {
+        input              string
+        expectedErrors     int
+        expectedStatements []string
+    }{
+        {"let x = add(1, 2; let y = 3; y;", 1, []string{"let y = 3;", "y"}},
+        {"let x = add(1, 2 let y = 3; y;", 1, []string{"let y = 3;", "y"}},
+        {"let = 5; let z = 10;", 1, []string{"let z = 10;"}},
+        {"let = 1; let y 2; let z = 3;", 2, []string{"let z = 3;"}},
+        {"if (x { y } let a = 1;", 1, []string{"let a = 1;"}},
+        {"let a = [1, 2 + ; return a;", 1, []string{"return a;"}},
+        {"let f = fn(x) { let = 1; x }; f(2);", 1, []string{"let f = fn(x) x;", "f(2)"}},
+        {"let f = fn(x) { x + }; f(2);", 1, []string{"let f = fn(x) ;", "f(2)"}},
+        {") ) ) let a = 1;", 1, []string{"let a = 1;"}},
+        {"} let a = 1;", 1, []string{"let a = 1;"}},
+        {"if (x { y; z } let a = 1;", 1, []string{"let a = 1;"}},
+        {"let f = fn() { if (x { y; z } let a = 1; a }; f();", 1, []string{"let f = fn() let a = 1;a;", "f()"}},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+
+        if len(p.Errors()) != tt.expectedErrors {
+            t.Errorf("%q: wrong number of errors. expected=%d, got=%d %q", tt.input, tt.expectedErrors,
+                len(p.Errors()), p.Errors())
+        }
+
+        if len(program.Statements) != len(tt.expectedStatements) {
+            t.Errorf("%q: wrong number of statements. expected=%d, got=%d", tt.input,
+                len(tt.expectedStatements), len(program.Statements))
+            continue
+        }
+        for i, stmt := range program.Statements {
+            if stmt.String() != tt.expectedStatements[i] {
+                t.Errorf("%q: statement %d wrong. expected=%q, got=%q", tt.input, i, tt.expectedStatements[i], stmt.String())
+            }
+        }
+    }
+}
+
+
+func TestTryExpression(t *testing.T) {
+    tests := []struct {
+        input           string
+        expectedParam   string
+        expectedCatch   bool
+        expectedFinally bool
+    }{
+        {"try { x } catch (e) { y }", "e", true, false},
+        {"try { x } catch { y }", "", true, false},
+        {"try { x } finally { z }", "", false, true},
+        {"try { x } catch (err) { y } finally { z }", "err", true, true},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        if len(program.Statements) != 1 {
+            t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+        }
+        stmt := program.Statements[0].(*ast.ExpressionStatement)
+        exp, ok := stmt.Expression.(*ast.TryExpression)
+        if !ok {
+            t.Fatalf("stmt.Expression is not ast.TryExpression. got=%T", stmt.Expression)
+        }
+
+        if !testIdentifier(t, exp.Block.Statements[0].(*ast.ExpressionStatement).Expression, "x") {
+            return
+        }
+        if tt.expectedParam == "" && exp.Param != nil {
+            t.Errorf("exp.Param not nil. got=%s", exp.Param)
+        }
+        if tt.expectedParam != "" && !testIdentifier(t, exp.Param, tt.expectedParam) {
+            return
+        }
+        if (exp.Catch != nil) != tt.expectedCatch {
+            t.Errorf("exp.Catch wrong. got=%v", exp.Catch)
+        }
+        if (exp.Finally != nil) != tt.expectedFinally {
+            t.Errorf("exp.Finally wrong. got=%v", exp.Finally)
+        }
+    }
+}
+
+
+func TestTryWithoutCatchOrFinally(t *testing.T) {
+    l := lexer.New("try { x }; let y = 1;")
+    p := New(l)
+    program := p.ParseProgram()
+
+    expected := "1:10: Expected next token to be CATCH, got ; instead"
+    if len(p.Errors()) != 1 || p.Errors()[0] != expected {
+        t.Fatalf("wrong errors. expected=[%q], got=%q", expected, p.Errors())
+    }
+    if len(program.Statements) != 1 || program.Statements[0].String() != "let y = 1;" {
+        t.Errorf("wrong statements after recovering. got=%q", program.String())
+    }
+}
+
+
+func TestThrowStatement(t *testing.T) {
+    l := lexer.New(`throw "oops" + x;`)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt, ok := program.Statements[0].(*ast.ThrowStatement)
+    if !ok {
+        t.Fatalf("program.Statements[0] is not ast.ThrowStatement. got=%T", program.Statements[0])
+    }
+    if stmt.String() != "throw (oops + x);" {
+        t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+    }
+}
+
+
+func TestWhileStatement(t *testing.T) {
+    l := lexer.New("while (x < y) { x; break; }")
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    if len(program.Statements) != 1 {
+        t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+    }
+    stmt, ok := program.Statements[0].(*ast.WhileStatement)
+    if !ok {
+        t.Fatalf("program.Statements[0] is not ast.WhileStatement. got=%T", program.Statements[0])
+    }
+    if !testInfixExpression(t, stmt.Condition, "x", "<", "y") {
+        return
+    }
+    if len(stmt.Body.Statements) != 2 {
+        t.Fatalf("body is not 2 statements. got=%d", len(stmt.Body.Statements))
+    }
+    branch, ok := stmt.Body.Statements[1].(*ast.BranchStatement)
+    if !ok || branch.Token.Type != token.BREAK {
+        t.Errorf("body.Statements[1] is not break. got=%T (%s)", stmt.Body.Statements[1], stmt.Body.Statements[1])
+    }
+}
+
+
+func TestForStatement(t *testing.T) {
+    tests := []struct {
+        input         string
+        expectedKey   string
+        expectedValue string
+        expectedString string
+    }{
+        {"for (x in xs) { continue }", "x", "", "for(x in xs) continue;"},
+        {"for (k, v in {1: 2}) { k }", "k", "v", "for(k, v in {1:2}) k"},
+        {"for (i in range(1 + 2)) { }", "i", "", "for(i in range((1 + 2))) "},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        if len(program.Statements) != 1 {
+            t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+        }
+        stmt, ok := program.Statements[0].(*ast.ForStatement)
+        if !ok {
+            t.Fatalf("program.Statements[0] is not ast.ForStatement. got=%T", program.Statements[0])
+        }
+        if !testIdentifier(t, stmt.Key, tt.expectedKey) {
+            return
+        }
+        if tt.expectedValue == "" && stmt.Value != nil {
+            t.Errorf("stmt.Value not nil. got=%s", stmt.Value)
+        }
+        if tt.expectedValue != "" && !testIdentifier(t, stmt.Value, tt.expectedValue) {
+            return
+        }
+        if stmt.String() != tt.expectedString {
+            t.Errorf("stmt.String() wrong. expected=%q, got=%q", tt.expectedString, stmt.String())
+        }
+    }
+}
+
+
+func TestBranchOutsideLoop(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected []string
+    }{
+        {"break; 1", []string{"1:1: break outside of a loop"}},
+        {"if (x) { continue }", []string{"1:10: continue outside of a loop"}},
+        {"while (x) { fn() { break }; break }", []string{"1:20: break outside of a loop"}},
+        {"for (x in xs) { while (x) { continue }; break }", []string{}},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) != len(tt.expected) {
+            t.Errorf("wrong errors for %q. expected=%q, got=%q", tt.input, tt.expected, errors)
+            continue
+        }
+        for i, msg := range tt.expected {
+            if errors[i] != msg {
+                t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, msg, errors[i])
+            }
+        }
+        for _, d := range p.Diagnostics() {
+            if d.Code != BRANCH_OUTSIDE_LOOP {
+                t.Errorf("wrong code. expected=%s, got=%s", BRANCH_OUTSIDE_LOOP, d.Code)
+            }
+        }
+    }
+}
+
+
+func TestAssignExpression(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected string
+    }{
+        {"x = 5", "x = 5"},
+        {"x = y = 1 + 2", "x = y = (1 + 2)"},
+        {"x += 2 * 3", "x += (2 * 3)"},
+        {"a[i] -= 1", "(a[i]) -= 1"},
+        {"h[\"k\"] = fn(x) { x }", "(h[k]) = fn(x) x"},
+        {"x *= y /= 2", "x *= y /= 2"},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        stmt := program.Statements[0].(*ast.ExpressionStatement)
+        if _, ok := stmt.Expression.(*ast.AssignExpression); !ok {
+            t.Fatalf("stmt.Expression is not ast.AssignExpression. got=%T", stmt.Expression)
+        }
+        if stmt.String() != tt.expected {
+            t.Errorf("expected=%q, got=%q", tt.expected, stmt.String())
+        }
+    }
+}
+
+
+func TestInvalidAssignmentTarget(t *testing.T) {
+    l := lexer.New("1 + x = 2; let y = 3;")
+    p := New(l)
+    program := p.ParseProgram()
+
+    diagnostics := p.Diagnostics()
+    if len(diagnostics) != 1 {
+        t.Fatalf("wrong number of diagnostics. expected=1, got=%d (%q)", len(diagnostics), p.Errors())
+    }
+    if diagnostics[0].Code != INVALID_ASSIGNMENT || diagnostics[0].String() != "1:7: cannot assign to (1 + x)" {
+        t.Errorf("wrong diagnostic. got=%s %q", diagnostics[0].Code, diagnostics[0].String())
+    }
+    if len(program.Statements) != 1 || program.Statements[0].String() != "let y = 3;" {
+        t.Errorf("wrong statements after recovering. got=%q", program.String())
+    }
+}
+
+
+func TestConstStatement(t *testing.T) {
+    l := lexer.New("const limit = 10;")
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    stmt, ok := program.Statements[0].(*ast.LetStatement)
+    if !ok {
+        t.Fatalf("program.Statements[0] is not ast.LetStatement. got=%T", program.Statements[0])
+    }
+    if stmt.Token.Type != token.CONST {
+        t.Errorf("stmt.Token.Type not CONST. got=%s", stmt.Token.Type)
+    }
+    if stmt.String() != "const limit = 10;" {
+        t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+    }
+}
+
+
+func TestConstMisuse(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected []string
+    }{
+        {"const x = 1; x = 2;", []string{"1:14: cannot assign to constant x"}},
+        {"const x = 1; x += 2;", []string{"1:14: cannot assign to constant x"}},
+        {"const x = 1; let x = 2;", []string{"1:18: cannot redeclare constant x"}},
+        {"const x = 1; const x = 2;", []string{"1:20: cannot redeclare constant x"}},
+        {"const x = 1; fn() { x = 2 }", []string{"1:21: cannot assign to constant x"}},
+        {"let x = 1; const x = 2; x = 3;", []string{"1:25: cannot assign to constant x"}},
+        // shadowed, or not a constant yet when the parser gets there
+        {"const x = 1; fn(x) { x = 2 }", []string{}},
+        {"const x = 1; fn() { let x = 2; x = 3 }", []string{}},
+        {"const x = 1; for (x in []) { x = 2 }", []string{}},
+        {"const x = 1; try { 1 } catch (x) { x = 2 }", []string{}},
+        {"let f = fn() { x = 2 }; const x = 1;", []string{}},
+        {"const a = [1]; a[0] = 2;", []string{}},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) != len(tt.expected) {
+            t.Errorf("wrong errors for %q. expected=%q, got=%q", tt.input, tt.expected, errors)
+            continue
+        }
+        for i, msg := range tt.expected {
+            if errors[i] != msg || p.Diagnostics()[i].Code != CONST_ASSIGNMENT {
+                t.Errorf("wrong error for %q. expected=%q, got=%s %q", tt.input, msg, p.Diagnostics()[i].Code, errors[i])
+            }
+        }
+        // the statements are still all there, nothing needed recovering from
+        if len(program.Statements) < 2 {
+            t.Errorf("statements missing for %q. got=%q", tt.input, program.String())
+        }
+    }
+}
+
+
+func TestHashLiteralKeepsOrder(t *testing.T) {
+    l := lexer.New(`{"c": 1, "a": 2 + 3, "b": x}`)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    expected := "{c:1, a:(2 + 3), b:x}"
+    for i := 0; i < 10; i++ {
+        if program.String() != expected {
+            t.Fatalf("program.String() wrong. expected=%q, got=%q", expected, program.String())
+        }
+    }
+}
+
+
+func TestStringLiteralErrors(t *testing.T) {
+    tests := []struct {
+        input      string
+        expected   []string
+        statements int
+    }{
+        {`let x = "abc`, []string{"1:9: string literal not terminated"}, 0},
+        {"let x = 1;\nputs(`abc", []string{"2:6: string literal not terminated"}, 1},
+        {`let x = "a\qb"; let y = 2;`, []string{`1:11: unknown escape sequence \q`}, 2},
+        {`let x = 1 "abc`, []string{"1:11: string literal not terminated"}, 1},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+
+        if len(p.Errors()) != len(tt.expected) {
+            t.Errorf("wrong errors for %q. expected=%q, got=%q", tt.input, tt.expected, p.Errors())
+            continue
+        }
+        for i, msg := range tt.expected {
+            if p.Errors()[i] != msg || p.Diagnostics()[i].Code != LEXICAL_ERROR {
+                t.Errorf("wrong error for %q. expected=%q, got=%s %q", tt.input, msg, p.Diagnostics()[i].Code, p.Errors()[i])
+            }
+        }
+        if len(program.Statements) != tt.statements {
+            t.Errorf("wrong number of statements for %q. expected=%d, got=%d", tt.input, tt.statements, len(program.Statements))
+        }
+    }
+}
+
+func TestInterpolatedString(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected string
+        parts    int
+    }{
+        {`"a ${x} b"`, `a ${x} b`, 3},
+        {`"${x}${y + 1}"`, `${x}${(y + 1)}`, 5},
+        {`"n: ${len("${a}")}"`, `n: ${len(${a})}`, 3},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        stmt := program.Statements[0].(*ast.ExpressionStatement)
+        str, ok := stmt.Expression.(*ast.InterpolatedString)
+        if !ok {
+            t.Fatalf("exp not *ast.InterpolatedString. got=%T", stmt.Expression)
+        }
+        if len(str.Parts) != tt.parts {
+            t.Errorf("wrong number of parts for %q. expected=%d, got=%d", tt.input, tt.parts, len(str.Parts))
+        }
+        if str.String() != tt.expected {
+            t.Errorf("expected=%q, got=%q", tt.expected, str.String())
+        }
+    }
+}
+
+func TestInterpolatedStringErrors(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected string
+    }{
+        {`"a ${x y} b"`, "1:8: Expected next token to be INTERP_END, got IDENT instead"},
+        {`"a ${} b"`, "1:6: no prefix parse function for INTERP_END found"},
+        {`"a ${x} b`, "1:1: string literal not terminated"},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        p.ParseProgram()
+
+        if len(p.Errors()) != 1 || p.Errors()[0] != tt.expected {
+            t.Errorf("wrong errors for %q. expected=%q, got=%q", tt.input, tt.expected, p.Errors())
+        }
+    }
+}
+
+func TestComments(t *testing.T) {
+    input := `
+// adds two numbers
+let add = fn(a, b) {
+    a + b // no need for return
+};
+/* let y = 1; */
+let x = add(1, /* the answer */ 2);
+`
+
+    l := lexer.New(input)
+    p := New(l)
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+
+    expected := "let add = fn(a, b) (a + b);let x = add(1, 2);"
+    if program.String() != expected {
+        t.Errorf("expected=%q, got=%q", expected, program.String())
+    }
+}
+
+func TestUnterminatedCommentError(t *testing.T) {
+    l := lexer.New("let x = 1;\nlet y = /* 2;")
+    p := New(l)
+    program := p.ParseProgram()
+
+    expected := []string{"2:9: comment not terminated"}
+    if len(p.Errors()) != 1 || p.Errors()[0] != expected[0] {
+        t.Errorf("wrong errors. expected=%q, got=%q", expected, p.Errors())
+    }
+    if len(program.Statements) != 1 {
+        t.Errorf("wrong number of statements. expected=1, got=%d", len(program.Statements))
+    }
+}
+
+func TestSliceExpression(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected string
+    }{
+        {"a[1:2]", "(a[1:2])"},
+        {"a[:2]", "(a[:2])"},
+        {"a[1:]", "(a[1:])"},
+        {"a[:]", "(a[:])"},
+        {"a[::2]", "(a[::2])"},
+        {"a[1:2:3]", "(a[1:2:3])"},
+        {"a[i + 1:-1:-1]", "(a[(i + 1):(-1):(-1)])"},
+        {"a[:][0]", "((a[:])[0])"},
+        {"a[{1: 2}[1]:]", "(a[({1:2}[1]):])"},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        if program.String() != tt.expected {
+            t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+        }
+    }
+}
+
+func TestSliceExpressionErrors(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected string
+    }{
+        {"a[1:2:3:4]", "1:8: Expected next token to be ], got : instead"},
+        {"a[1:2", "1:6: Expected next token to be ], got EOF instead"},
+        {"a[1:2] = 3", "1:8: cannot assign to (a[1:2])"},
+    }
+
+    for _, tt := range tests {
+        l := lexer.New(tt.input)
+        p := New(l)
+        p.ParseProgram()
+
+        if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+            t.Errorf("wrong errors for %q. expected=%q, got=%q", tt.input, tt.expected, p.Errors())
+        }
+    }
+}
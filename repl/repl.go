@@ -5,25 +5,44 @@ import (
 	"A-Plus-Plus/lexer"
 	"A-Plus-Plus/object"
 	"A-Plus-Plus/parser"
+	"A-Plus-Plus/token"
 	"bufio"
 	"fmt"
 	"io"
+	"strings"
 )
 
 const PROMPT = ">> "
+const CONTINUATION_PROMPT = ".. "
 
 func Start(in io.Reader, out io.Writer) {
     scanner := bufio.NewScanner(in)
     env := object.NewEnvironment()
+    lines := []string{}
 
     for {
-        fmt.Fprintf(out, PROMPT)
+        if len(lines) == 0 {
+            fmt.Fprintf(out, PROMPT)
+        } else {
+            fmt.Fprintf(out, CONTINUATION_PROMPT)
+        }
         scanned := scanner.Scan()
         if !scanned {
             return
         }
         line := scanner.Text()
-        l := lexer.New(line)
+
+        // An empty line while continuing means the user gave up, so evaluate what we have and let
+        // the parser say what is wrong with it
+        giveUp := len(lines) > 0 && strings.TrimSpace(line) == ""
+        lines = append(lines, line)
+        input := strings.Join(lines, "\n")
+        if !giveUp && isIncomplete(input) {
+            continue
+        }
+        lines = []string{}
+
+        l := lexer.New(input)
         p := parser.New(l)
         program := p.ParseProgram()
         if len(p.Errors()) != 0 {
@@ -36,7 +55,68 @@ func Start(in io.Reader, out io.Writer) {
             io.WriteString(out, evaluated.Inspect())
             io.WriteString(out, "\n")
         }
+        if errObj, ok := evaluated.(*object.Error); ok {
+            io.WriteString(out, errObj.StackTrace())
+        }
+    }
+}
+
+// Tokens that cannot end a statement, so more input has to follow
+var continuationTokens = map[token.TokenType]bool {
+    token.ASSIGN:          true,
+    token.PLUS_ASSIGN:     true,
+    token.MINUS_ASSIGN:    true,
+    token.ASTERISK_ASSIGN: true,
+    token.SLASH_ASSIGN:    true,
+    token.PLUS:            true,
+    token.MINUS:           true,
+    token.BANG:            true,
+    token.ASTERISK:        true,
+    token.SLASH:           true,
+    token.PERCENT:         true,
+    token.POWER:           true,
+    token.BIT_AND:         true,
+    token.BIT_OR:          true,
+    token.BIT_XOR:         true,
+    token.BIT_NOT:         true,
+    token.SHIFT_LEFT:      true,
+    token.SHIFT_RIGHT:     true,
+    token.LT:              true,
+    token.GT:              true,
+    token.LT_EQ:           true,
+    token.GT_EQ:           true,
+    token.EQ:              true,
+    token.NOT_EQ:          true,
+    token.AND:             true,
+    token.OR:              true,
+    token.COMMA:           true,
+    token.COLON:           true,
+    token.ELSE:            true,
+}
+
+// Reports whether input stops in the middle of a statement: an unclosed bracket or string, or a
+// trailing operator
+func isIncomplete(input string) bool {
+    l := lexer.New(input)
+    depth := 0
+    last := token.Token{Type: token.EOF}
+
+    for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+        switch tok.Type {
+        case token.LPAREN, token.LBRACE, token.LBRACKET, token.INTERP_START:
+            depth += 1
+        case token.RPAREN, token.RBRACE, token.RBRACKET, token.INTERP_END:
+            depth -= 1
+        case token.ILLEGAL:
+            // a string or comment that is not closed, which may still be on the next line
+            if strings.HasPrefix(tok.Literal, "\"") || strings.HasPrefix(tok.Literal, "`") || strings.HasPrefix(tok.Literal, "/*") {
+                return true
+            }
+        }
+        last = tok
     }
+
+    return depth > 0 || continuationTokens[last.Type]
 }
 
 func printParserErrors(out io.Writer, errors []string) {
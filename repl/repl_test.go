@@ -0,0 +1,81 @@
+package repl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestIsIncomplete(t *testing.T) {
+    tests := []struct {
+        input    string
+        expected bool
+    }{
+        {"", false},
+        {"let x = 5;", false},
+        {"let x = 5", false},
+        {"let add = fn(x, y) {", true},
+        {"let add = fn(x, y) {\n x + y\n}", false},
+        {"add(1,", true},
+        {"[1, 2", true},
+        {`{"a": 1,`, true},
+        {`"hello`, true},
+        {`"hello"`, false},
+        {`"say \"hi`, true},
+        {`"say \"hi\""`, false},
+        {"`raw\nstring", true},
+        {"`raw\nstring`", false},
+        {`"a ${x`, true},
+        {`"a ${f(x,`, true},
+        {`"a ${x} b`, true},
+        {`"a ${x} b"`, false},
+        {"let x = 5 // five", false},
+        {"let x = 5 + // and", true},
+        {"/* a\ncomment", true},
+        {"/* a /* nested */ comment */", false},
+        {"x /* a /* nested */", true},
+        {"let x = 5 +", true},
+        {"let x =", true},
+        {"if (x) { 1 } else", true},
+        {"1 + 2)", false},
+        {"}", false},
+    }
+
+    for _, tt := range tests {
+        if got := isIncomplete(tt.input); got != tt.expected {
+            t.Errorf("isIncomplete(%q) wrong. expected=%t, got=%t", tt.input, tt.expected, got)
+        }
+    }
+}
+
+func TestStartMultiLine(t *testing.T) {
+    input := `let add = fn(x, y) {
+    x + y
+};
+add(1,
+2)
+let broken = fn() {
+
+5 * 5
+`
+    var out bytes.Buffer
+    Start(strings.NewReader(input), &out)
+
+    expected := ">> .. .. >> .. 3\n>> .. " + "!!!! EMOTIONAL DAMAGE !!!!\n" +
+        "\t2:1: Expected next token to be }, got EOF instead\n>> 25\n>> "
+    if out.String() != expected {
+        t.Errorf("wrong output.\nexpected=%q\ngot=     %q", expected, out.String())
+    }
+}
+
+func TestStartPrintsStackTrace(t *testing.T) {
+    input := "let f = fn() { 1 + true };\nf()\n"
+    var out bytes.Buffer
+    Start(strings.NewReader(input), &out)
+
+    expected := ">> >> ERROR: 1:16: type mismatch: INTEGER + BOOLEAN\n" +
+        "    at f (1:16)\n    at <main> (1:1)\n>> "
+    if out.String() != expected {
+        t.Errorf("wrong output.\nexpected=%q\ngot=     %q", expected, out.String())
+    }
+}
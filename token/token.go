@@ -1,10 +1,48 @@
 package token
 
+import "fmt"
+
 type TokenType string
 
 type Token struct {
     Type TokenType
-    Literal string
+    Literal string // for strings, the value with escapes decoded
+    Pos Position // where the token starts in the source
+    End Position // just past the end of the token in the source, zero if it was not lexed
+
+    Comments []Comment // the comments between the previous token and this one, if the lexer keeps them
+}
+
+// A // or /* */ comment. The lexer skips them, unless it is told to keep them for tools like
+// formatters that need to put them back
+type Comment struct {
+    Text string // with the // or /* */
+    Pos Position
+}
+
+type Position struct {
+    Filename string // optional, empty when lexing a plain string
+    Offset int // byte offset, starting at 0
+    Line int // starting at 1
+    Column int // starting at 1
+}
+
+// A zero Position (Line == 0) means we don't know where something came from
+func (p Position) IsValid() bool {
+    return p.Line > 0
+}
+
+func (p Position) String() string {
+    if !p.IsValid() {
+        if p.Filename != "" {
+            return p.Filename
+        }
+        return "-"
+    }
+    if p.Filename != "" {
+        return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+    }
+    return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
 // TokenTypes
@@ -15,8 +53,14 @@ const (
     // Identifiers + literals
     IDENT = "IDENT"
     INT = "INT"
+    FLOAT = "FLOAT"
     STRING = "STRING"
 
+    // "a ${x} b ${y} c" is INTERP_START("a "), x, INTERP_MID(" b "), y, INTERP_END(" c")
+    INTERP_START = "INTERP_START"
+    INTERP_MID = "INTERP_MID"
+    INTERP_END = "INTERP_END"
+
     // Operators
     ASSIGN = "="
     PLUS = "+"
@@ -24,13 +68,32 @@ const (
     BANG = "!"
     ASTERISK = "*"
     SLASH = "/"
+    PERCENT = "%"
+    POWER = "**"
+
+    BIT_AND = "&"
+    BIT_OR = "|"
+    BIT_XOR = "^"
+    BIT_NOT = "~"
+    SHIFT_LEFT = "<<"
+    SHIFT_RIGHT = ">>"
 
     LT = "<"
     GT = ">"
+    LT_EQ = "<="
+    GT_EQ = ">="
 
     EQ = "=="
     NOT_EQ = "!="
 
+    AND = "&&"
+    OR = "||"
+
+    PLUS_ASSIGN = "+="
+    MINUS_ASSIGN = "-="
+    ASTERISK_ASSIGN = "*="
+    SLASH_ASSIGN = "/="
+
     // Delimiters
     COMMA = ","
     SEMICOLON = ";"
@@ -46,26 +109,57 @@ const (
     // Keywords
     FUNCTION = "FUNCTION"
     LET      = "LET"
+    CONST    = "CONST"
     TRUE     = "TRUE"
     FALSE    = "FALSE"
     IF       = "IF"
     ELSE     = "ELSE"
     RETURN   = "RETURN"
+    TRY      = "TRY"
+    CATCH    = "CATCH"
+    FINALLY  = "FINALLY"
+    THROW    = "THROW"
+    WHILE    = "WHILE"
+    FOR      = "FOR"
+    IN       = "IN"
+    BREAK    = "BREAK"
+    CONTINUE = "CONTINUE"
 )
 
 var keywords = map[string]TokenType {
     "fn": FUNCTION,
     "let": LET,
+    "const": CONST,
     "true": TRUE,
     "false":  FALSE,
     "if":     IF,
     "else":   ELSE,
     "return": RETURN,
+    "try":     TRY,
+    "catch":   CATCH,
+    "finally": FINALLY,
+    "throw":   THROW,
+    "while":    WHILE,
+    "for":      FOR,
+    "in":       IN,
+    "break":    BREAK,
+    "continue": CONTINUE,
 }
 
 var two_char_operators = map[string]TokenType {
     "==": EQ,
     "!=": NOT_EQ,
+    "<=": LT_EQ,
+    ">=": GT_EQ,
+    "**": POWER,
+    "<<": SHIFT_LEFT,
+    ">>": SHIFT_RIGHT,
+    "&&": AND,
+    "||": OR,
+    "+=": PLUS_ASSIGN,
+    "-=": MINUS_ASSIGN,
+    "*=": ASTERISK_ASSIGN,
+    "/=": SLASH_ASSIGN,
 }
 
 func LookupOperator(op string) TokenType {